@@ -0,0 +1,163 @@
+// Package archivefs provides read-only ocflfs.FS implementations for zip and
+// tar files.
+package archivefs
+
+import (
+	"cmp"
+	"context"
+	"io"
+	"io/fs"
+	"iter"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+)
+
+// FS is a read-only ocflfs.FS for the files in a zip or tar file.
+type FS struct {
+	format string            // "zip" or "tar"
+	name   string            // archive file name
+	files  map[string]*entry // regular files by path
+	dirs   map[string][]fs.DirEntry
+	listed map[string]bool // paths included in their parent's entries
+	closer io.Closer
+}
+
+var _ ocflfs.DirEntriesFS = (*FS)(nil)
+
+// entry is a regular file in the archive.
+type entry struct {
+	info fs.FileInfo
+	open func() (io.ReadCloser, error)
+}
+
+func newFS(format, name string, closer io.Closer) *FS {
+	return &FS{
+		format: format,
+		name:   name,
+		files:  map[string]*entry{},
+		dirs:   map[string][]fs.DirEntry{".": nil},
+		listed: map[string]bool{},
+		closer: closer,
+	}
+}
+
+// Format returns the archive format: "zip" or "tar".
+func (fsys *FS) Format() string { return fsys.format }
+
+// Name returns the archive's file name.
+func (fsys *FS) Name() string { return fsys.name }
+
+// Close closes the archive file.
+func (fsys *FS) Close() error { return fsys.closer.Close() }
+
+// OpenFile opens the named file in the archive.
+func (fsys *FS) OpenFile(ctx context.Context, name string) (fs.File, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, &fs.PathError{Op: "openfile", Path: name, Err: err}
+	}
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "openfile", Path: name, Err: fs.ErrInvalid}
+	}
+	e := fsys.files[name]
+	if e == nil {
+		if _, isDir := fsys.dirs[name]; isDir {
+			return nil, &fs.PathError{Op: "openfile", Path: name, Err: ocflfs.ErrNotFile}
+		}
+		return nil, &fs.PathError{Op: "openfile", Path: name, Err: fs.ErrNotExist}
+	}
+	rc, err := e.open()
+	if err != nil {
+		return nil, &fs.PathError{Op: "openfile", Path: name, Err: err}
+	}
+	return &file{ReadCloser: rc, info: e.info}, nil
+}
+
+// DirEntries implements ocflfs.DirEntriesFS.
+func (fsys *FS) DirEntries(ctx context.Context, name string) iter.Seq2[fs.DirEntry, error] {
+	return func(yield func(fs.DirEntry, error) bool) {
+		if !fs.ValidPath(name) {
+			yield(nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid})
+			return
+		}
+		entries, ok := fsys.dirs[name]
+		if !ok {
+			yield(nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist})
+			return
+		}
+		for _, e := range entries {
+			if err := ctx.Err(); err != nil {
+				yield(nil, &fs.PathError{Op: "readdir", Path: name, Err: err})
+				return
+			}
+			if !yield(e, nil) {
+				return
+			}
+		}
+	}
+}
+
+// add adds a regular file or directory to the index. Names are cleaned and
+// entries with invalid names or other file types are ignored.
+func (fsys *FS) add(name string, info fs.FileInfo, open func() (io.ReadCloser, error)) {
+	name = path.Clean(strings.TrimPrefix(name, "/"))
+	if !fs.ValidPath(name) || name == "." {
+		return
+	}
+	if info.Mode().IsRegular() {
+		fsys.files[name] = &entry{info: namedInfo{FileInfo: info, name: path.Base(name)}, open: open}
+	} else if !info.IsDir() {
+		return
+	}
+	// add entries for name and its parents to their parent directories.
+	for child := name; child != "."; child = path.Dir(child) {
+		if fsys.listed[child] {
+			break
+		}
+		fsys.listed[child] = true
+		var childInfo fs.FileInfo = dirInfo(path.Base(child))
+		if child == name {
+			childInfo = namedInfo{FileInfo: info, name: path.Base(name)}
+		}
+		if _, exists := fsys.dirs[child]; !exists && childInfo.IsDir() {
+			fsys.dirs[child] = nil
+		}
+		dir := path.Dir(child)
+		fsys.dirs[dir] = append(fsys.dirs[dir], fs.FileInfoToDirEntry(childInfo))
+	}
+}
+
+// sort sorts directory entries by name.
+func (fsys *FS) sort() {
+	for _, entries := range fsys.dirs {
+		slices.SortFunc(entries, func(a, b fs.DirEntry) int { return cmp.Compare(a.Name(), b.Name()) })
+	}
+}
+
+// file is an open file in the archive.
+type file struct {
+	io.ReadCloser
+	info fs.FileInfo
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+type namedInfo struct {
+	fs.FileInfo
+	name string
+}
+
+func (i namedInfo) Name() string { return i.name }
+
+// dirInfo is a directory that isn't in the archive's headers.
+type dirInfo string
+
+func (d dirInfo) Name() string       { return string(d) }
+func (d dirInfo) Size() int64        { return 0 }
+func (d dirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0o555 }
+func (d dirInfo) ModTime() time.Time { return time.Time{} }
+func (d dirInfo) IsDir() bool        { return true }
+func (d dirInfo) Sys() any           { return nil }
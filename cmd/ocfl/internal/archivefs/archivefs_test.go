@@ -0,0 +1,78 @@
+package archivefs_test
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-go"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/archivefs"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/testutil"
+)
+
+func TestArchiveFS(t *testing.T) {
+	ctx := context.Background()
+	tmp, fixtures := testutil.TempDirTestData(t, `testdata/object-fixtures/1.1/good-objects/spec-ex-full`)
+	objDir := fixtures[0]
+	for _, ext := range []string{".zip", ".tar"} {
+		t.Run(ext, func(t *testing.T) {
+			name := filepath.Join(tmp, "object"+ext)
+			testutil.ArchiveDir(t, objDir, name, "packed/obj")
+			open := archivefs.OpenZip
+			if ext == ".tar" {
+				open = archivefs.OpenTar
+			}
+			fsys, err := open(name)
+			be.NilErr(t, err)
+			defer fsys.Close()
+			// validate the object, including digests
+			result := ocfl.ValidateObject(ctx, fsys, "packed/obj")
+			be.NilErr(t, result.Err())
+			// read a file
+			got, err := ocflfs.ReadAll(ctx, fsys, "packed/obj/v1/content/foo/bar.xml")
+			be.NilErr(t, err)
+			expect, err := os.ReadFile(filepath.Join(objDir, "v1", "content", "foo", "bar.xml"))
+			be.NilErr(t, err)
+			be.Equal(t, string(expect), string(got))
+			info, err := ocflfs.StatFile(ctx, fsys, "packed/obj/v1/content/foo/bar.xml")
+			be.NilErr(t, err)
+			be.Equal(t, "bar.xml", info.Name())
+			// directory entries
+			entries, err := ocflfs.ReadDir(ctx, fsys, "packed")
+			be.NilErr(t, err)
+			be.Equal(t, 1, len(entries))
+			be.True(t, entries[0].IsDir())
+			_, err = ocflfs.ReadDir(ctx, fsys, "missing")
+			be.True(t, err != nil)
+			_, err = fsys.OpenFile(ctx, "packed/obj/v1")
+			be.True(t, err != nil)
+			_, err = fsys.OpenFile(ctx, "missing.txt")
+			be.True(t, err != nil)
+			// read-only
+			_, err = ocflfs.Write(ctx, fsys, "new.txt", nil)
+			be.True(t, err != nil)
+		})
+	}
+	t.Run("compressed tar", func(t *testing.T) {
+		name := filepath.Join(tmp, "object.tar.gz")
+		f, err := os.Create(name)
+		be.NilErr(t, err)
+		gw := gzip.NewWriter(f)
+		be.NilErr(t, gw.Close())
+		be.NilErr(t, f.Close())
+		_, err = archivefs.OpenTar(name)
+		be.True(t, err != nil)
+		be.In(t, "compressed", err.Error())
+	})
+	t.Run("missing file", func(t *testing.T) {
+		_, err := archivefs.OpenZip(filepath.Join(tmp, "missing.zip"))
+		be.True(t, err != nil)
+		be.True(t, errors.Is(err, fs.ErrNotExist))
+	})
+}
@@ -0,0 +1,64 @@
+package archivefs
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// gzip files start with these bytes
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// OpenTar returns an FS for the uncompressed tar file name. The FS should be
+// closed when it is no longer needed.
+func OpenTar(name string) (*FS, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("opening tar file: %w", err)
+	}
+	fsys, err := readTar(f, name)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("reading tar file: %w", err)
+	}
+	return fsys, nil
+}
+
+func readTar(f *os.File, name string) (*FS, error) {
+	magic := make([]byte, len(gzipMagic))
+	if _, err := io.ReadFull(f, magic); err == nil && bytes.Equal(magic, gzipMagic) {
+		return nil, errors.New("compressed tar files aren't supported: decompress the file first")
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	fsys := newFS("tar", name, f)
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		// tar.Reader reads headers without buffering, so the file's offset is
+		// the start of the entry's contents.
+		offset, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeDir {
+			continue // links, sparse files, etc. aren't supported
+		}
+		size := hdr.Size
+		fsys.add(hdr.Name, hdr.FileInfo(), func() (io.ReadCloser, error) {
+			return io.NopCloser(io.NewSectionReader(f, offset, size)), nil
+		})
+	}
+	fsys.sort()
+	return fsys, nil
+}
@@ -0,0 +1,22 @@
+package archivefs
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+)
+
+// OpenZip returns an FS for the zip file name. The FS should be closed when
+// it is no longer needed.
+func OpenZip(name string) (*FS, error) {
+	zr, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, fmt.Errorf("opening zip file: %w", err)
+	}
+	fsys := newFS("zip", name, zr)
+	for _, f := range zr.File {
+		fsys.add(f.Name, f.FileInfo(), func() (io.ReadCloser, error) { return f.Open() })
+	}
+	fsys.sort()
+	return fsys, nil
+}
@@ -0,0 +1,294 @@
+// Package cache provides an on-disk cache for files read from remote storage
+// roots. Content files are stored by digest, so cached files are immutable.
+// Inventories are cached by location and are only used if they match the
+// digest in the inventory's sidecar file.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	blobsDir = "blobs" // cached file contents, by digest
+	refsDir  = "refs"  // inventory locations, by hash of the location
+)
+
+// Cache is a directory with cached files. It is safe to use the same
+// directory from multiple processes.
+type Cache struct {
+	dir     string
+	maxSize int64
+	mu      sync.Mutex // guards size and serializes eviction
+	size    int64      // total size of cached files, or -1 if it isn't known
+}
+
+// Stats are statistics for the cache's contents.
+type Stats struct {
+	Files int
+	Size  int64
+}
+
+// Open returns a Cache using the directory dir, which is created if it doesn't
+// exist. When the total size of cached files exceeds maxSize, the least
+// recently used files are removed.
+func Open(dir string, maxSize int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating cache directory: %w", err)
+	}
+	return &Cache{dir: dir, maxSize: maxSize, size: -1}, nil
+}
+
+// Dir returns the cache directory.
+func (c *Cache) Dir() string { return c.dir }
+
+// MaxSize returns the cache's maximum size in bytes.
+func (c *Cache) MaxSize() int64 { return c.maxSize }
+
+// Stats returns the number and total size of cached files.
+func (c *Cache) Stats() (Stats, error) {
+	blobs, err := c.blobs()
+	if err != nil {
+		return Stats{}, err
+	}
+	stats := Stats{Files: len(blobs)}
+	for _, b := range blobs {
+		stats.Size += b.size
+	}
+	return stats, nil
+}
+
+// Clear removes all cached files.
+func (c *Cache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.size = -1
+	for _, name := range []string{blobsDir, refsDir} {
+		if err := os.RemoveAll(filepath.Join(c.dir, name)); err != nil {
+			return fmt.Errorf("clearing cache: %w", err)
+		}
+	}
+	return nil
+}
+
+// open opens the cached file with the digest. It returns an error wrapping
+// fs.ErrNotExist if the file isn't cached. The file's access time, used for
+// eviction, is updated.
+func (c *Cache) open(alg, digest string) (*os.File, error) {
+	name := c.blobPath(alg, digest)
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	_ = os.Chtimes(name, now, now)
+	return f, nil
+}
+
+// put adds the contents of the file at tmpName to the cache as the file with
+// the digest. tmpName must be in the cache directory.
+func (c *Cache) put(tmpName string, alg, digest string) error {
+	name := c.blobPath(alg, digest)
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	info, err := os.Stat(tmpName)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(name); err == nil {
+		// already cached
+		return os.Remove(tmpName)
+	}
+	if err := os.Rename(tmpName, name); err != nil {
+		return err
+	}
+	return c.added(info.Size())
+}
+
+// added updates the cache's size after a file of the given size is added. The
+// cache directory is only walked if the size isn't known or it exceeds the
+// maximum.
+func (c *Cache) added(size int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.size >= 0 {
+		c.size += size
+		if c.size <= c.maxSize {
+			return nil
+		}
+	}
+	return c.evict()
+}
+
+// putBytes adds b to the cache as the file with the digest.
+func (c *Cache) putBytes(b []byte, alg, digest string) error {
+	tmp, err := c.tempFile()
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(b)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := c.put(tmp.Name(), alg, digest); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// tempFile creates a new temporary file in the cache directory.
+func (c *Cache) tempFile() (*os.File, error) {
+	return os.CreateTemp(c.dir, ".tmp-*")
+}
+
+// ref is the cached digest for a file location.
+type ref struct {
+	Alg     string    `json:"alg"`
+	Digest  string    `json:"digest"`
+	ModTime time.Time `json:"modtime,omitzero"`
+}
+
+// getRef returns the cached digest for the location. It returns an error
+// wrapping fs.ErrNotExist if there isn't one.
+func (c *Cache) getRef(location string) (ref, error) {
+	var r ref
+	b, err := os.ReadFile(c.refPath(location))
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(b, &r); err != nil || r.Alg == "" || r.Digest == "" {
+		return r, fmt.Errorf("invalid cache entry for %s: %w", location, fs.ErrNotExist)
+	}
+	return r, nil
+}
+
+// setRef sets the cached digest for the location.
+func (c *Cache) setRef(location string, r ref) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	name := c.refPath(location)
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	tmp, err := c.tempFile()
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(b)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// evict sets the cache's size from the files in the blobs directory. If the
+// size exceeds the maximum, the least recently used files are removed until
+// it's under 90% of the maximum, so that eviction doesn't run again on the
+// next put. The caller must hold c.mu.
+func (c *Cache) evict() error {
+	blobs, err := c.blobs()
+	if err != nil {
+		return err
+	}
+	var size int64
+	for _, b := range blobs {
+		size += b.size
+	}
+	c.size = size
+	if size <= c.maxSize {
+		return nil
+	}
+	target := c.maxSize / 10 * 9
+	slices.SortFunc(blobs, func(a, b blob) int { return a.used.Compare(b.used) })
+	for _, b := range blobs {
+		if c.size <= target {
+			break
+		}
+		if err := os.Remove(b.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		c.size -= b.size
+	}
+	return nil
+}
+
+// blob is a file in the cache's blobs directory
+type blob struct {
+	path string
+	size int64
+	used time.Time
+}
+
+func (c *Cache) blobs() ([]blob, error) {
+	var blobs []blob
+	err := filepath.WalkDir(filepath.Join(c.dir, blobsDir), func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil // removed by another process
+			}
+			return err
+		}
+		blobs = append(blobs, blob{path: name, size: info.Size(), used: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading cache: %w", err)
+	}
+	return blobs, nil
+}
+
+func (c *Cache) blobPath(alg, digest string) string {
+	digest = strings.ToLower(digest)
+	prefix := digest[:min(2, len(digest))]
+	return filepath.Join(c.dir, blobsDir, strings.ReplaceAll(alg, "/", "-"), prefix, digest)
+}
+
+func (c *Cache) refPath(location string) string {
+	sum := sha256.Sum256([]byte(location))
+	key := hex.EncodeToString(sum[:])
+	return filepath.Join(c.dir, refsDir, key[:2], key)
+}
+
+// validDigest returns true if digest is a hex-encoded digest value, which can
+// be used as a file name.
+func validDigest(digest string) bool {
+	if digest == "" {
+		return false
+	}
+	_, err := hex.DecodeString(digest)
+	return err == nil
+}
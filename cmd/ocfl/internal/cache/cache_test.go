@@ -0,0 +1,114 @@
+package cache_test
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-go"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/cache"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/testutil"
+)
+
+func TestFS(t *testing.T) {
+	ctx := context.Background()
+	tmp, fixtures := testutil.TempDirTestData(t, `testdata/object-fixtures/1.1/good-objects/spec-ex-full`)
+	objDir := fixtures[0]
+	backend := &countingFS{FS: ocflfs.DirFS(objDir), opened: map[string]int{}}
+	c, err := cache.Open(filepath.Join(tmp, "cache"), 1<<20)
+	be.NilErr(t, err)
+
+	// readObject reads the object's inventory and the contents of all its
+	// files using a new cache FS.
+	readObject := func(t *testing.T) {
+		t.Helper()
+		obj, err := ocfl.NewObject(ctx, c.Wrap(backend, "test"), ".", ocfl.ObjectMustExist())
+		be.NilErr(t, err)
+		vfs, err := obj.VersionFS(ctx, 0)
+		be.NilErr(t, err)
+		err = fs.WalkDir(vfs, ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			_, err = fs.ReadFile(vfs, name)
+			return err
+		})
+		be.NilErr(t, err)
+	}
+	t.Run("first read", func(t *testing.T) {
+		readObject(t)
+		be.Equal(t, 1, backend.count("inventory.json"))
+		be.Equal(t, 1, backend.count("v2/content/foo/bar.xml"))
+		stats, err := c.Stats()
+		be.NilErr(t, err)
+		be.Equal(t, 4, stats.Files) // inventory and 3 content files
+	})
+	t.Run("cached read", func(t *testing.T) {
+		backend.reset()
+		readObject(t)
+		be.Equal(t, 0, backend.count("inventory.json"))
+		be.Equal(t, 1, backend.count("inventory.json.sha512"))
+		be.Equal(t, 0, backend.count("v2/content/foo/bar.xml"))
+	})
+	t.Run("changed inventory", func(t *testing.T) {
+		backend.reset()
+		sidecar := filepath.Join(objDir, "inventory.json.sha512")
+		orig, err := os.ReadFile(sidecar)
+		be.NilErr(t, err)
+		be.NilErr(t, os.WriteFile(sidecar, []byte("abcd inventory.json\n"), 0o644))
+		_, err = ocfl.NewObject(ctx, c.Wrap(backend, "test"), ".", ocfl.ObjectMustExist())
+		be.True(t, err != nil) // sidecar doesn't match
+		be.Equal(t, 1, backend.count("inventory.json"))
+		be.NilErr(t, os.WriteFile(sidecar, orig, 0o644))
+	})
+	t.Run("eviction", func(t *testing.T) {
+		small, err := cache.Open(filepath.Join(tmp, "small-cache"), 1024)
+		be.NilErr(t, err)
+		fsys := small.Wrap(backend, "test")
+		_, err = ocfl.NewObject(ctx, fsys, ".", ocfl.ObjectMustExist())
+		be.NilErr(t, err)
+		// image.tiff is larger than the cache
+		_, err = ocflfs.ReadAll(ctx, fsys, "v1/content/image.tiff")
+		be.NilErr(t, err)
+		stats, err := small.Stats()
+		be.NilErr(t, err)
+		be.True(t, stats.Size <= 1024)
+	})
+	t.Run("clear", func(t *testing.T) {
+		be.NilErr(t, c.Clear())
+		stats, err := c.Stats()
+		be.NilErr(t, err)
+		be.Equal(t, 0, stats.Files)
+	})
+}
+
+// countingFS counts the number of times files are opened.
+type countingFS struct {
+	ocflfs.FS
+	mu     sync.Mutex
+	opened map[string]int
+}
+
+func (f *countingFS) OpenFile(ctx context.Context, name string) (fs.File, error) {
+	f.mu.Lock()
+	f.opened[name]++
+	f.mu.Unlock()
+	return f.FS.OpenFile(ctx, name)
+}
+
+func (f *countingFS) count(name string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.opened[name]
+}
+
+func (f *countingFS) reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	clear(f.opened)
+}
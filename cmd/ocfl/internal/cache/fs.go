@@ -0,0 +1,361 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"iter"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/srerickson/ocfl-go/digest"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+)
+
+const inventoryFile = "inventory.json"
+
+// versionDir matches names of object version directories
+var versionDir = regexp.MustCompile(`^v\d+$`)
+
+// FS is an ocflfs.FS that reads inventories and content files through the
+// cache.
+type FS struct {
+	fsys     ocflfs.FS
+	cache    *Cache
+	location string    // identifies fsys in the cache
+	self     ocflfs.FS // FS or WriteFS returned by Wrap
+
+	mu       sync.RWMutex
+	content  map[string]ref    // digests for content paths, from inventories
+	sidecars map[string][]byte // inventory sidecars read while validating cached inventories
+}
+
+// WriteFS is an FS for a backend that supports write operations. Writes go
+// directly to the backend.
+type WriteFS struct {
+	*FS
+}
+
+var (
+	_ ocflfs.DirEntriesFS = (*FS)(nil)
+	_ ocflfs.FileWalker   = (*FS)(nil)
+	_ ocflfs.CopyFS       = (*WriteFS)(nil)
+)
+
+// Wrap returns an ocflfs.FS that reads fsys through the cache. The location
+// uniquely identifies fsys, for example, with a URL. If fsys implements
+// ocflfs.WriteFS, so does the returned value (a *WriteFS); otherwise it is an
+// *FS.
+func (c *Cache) Wrap(fsys ocflfs.FS, location string) ocflfs.FS {
+	cfs := &FS{
+		fsys:     fsys,
+		cache:    c,
+		location: location,
+		content:  map[string]ref{},
+		sidecars: map[string][]byte{},
+	}
+	cfs.self = cfs
+	if _, ok := fsys.(ocflfs.WriteFS); ok {
+		wfs := &WriteFS{FS: cfs}
+		cfs.self = wfs
+		return wfs
+	}
+	return cfs
+}
+
+// Unwrap returns the wrapped FS.
+func (f *FS) Unwrap() ocflfs.FS { return f.fsys }
+
+// OpenFile opens the named file. Inventories are read from the cache if they
+// match their sidecar digest. Content files listed in previously read
+// inventories are read from the cache if they have been cached; otherwise,
+// they are cached as they are read.
+func (f *FS) OpenFile(ctx context.Context, name string) (fs.File, error) {
+	if path.Base(name) == inventoryFile {
+		return f.openInventory(ctx, name)
+	}
+	f.mu.RLock()
+	sidecar, isSidecar := f.sidecars[name]
+	contentRef, isContent := f.content[name]
+	f.mu.RUnlock()
+	switch {
+	case isSidecar:
+		return newMemFile(name, sidecar, time.Time{}), nil
+	case isContent:
+		return f.openContent(ctx, name, contentRef)
+	}
+	return f.fsys.OpenFile(ctx, name)
+}
+
+func (f *FS) DirEntries(ctx context.Context, name string) iter.Seq2[fs.DirEntry, error] {
+	return ocflfs.DirEntries(ctx, f.fsys, name)
+}
+
+func (f *FS) WalkFiles(ctx context.Context, dir string) iter.Seq2[*ocflfs.FileRef, error] {
+	return func(yield func(*ocflfs.FileRef, error) bool) {
+		for ref, err := range ocflfs.WalkFiles(ctx, f.fsys, dir) {
+			if ref != nil {
+				ref.FS = f.self
+			}
+			if !yield(ref, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// openInventory returns the inventory from the cache if it matches the
+// digest in its sidecar. Otherwise the inventory is read from the backend and
+// cached if it matches its sidecar.
+func (f *FS) openInventory(ctx context.Context, name string) (fs.File, error) {
+	location := f.location + "/" + name
+	if r, err := f.cache.getRef(location); err == nil {
+		sidecarName := name + "." + r.Alg
+		sidecar, err := ocflfs.ReadAll(ctx, f.fsys, sidecarName)
+		if err == nil && strings.EqualFold(sidecarDigest(sidecar), r.Digest) {
+			if cached, err := f.cache.open(r.Alg, r.Digest); err == nil {
+				b, err := io.ReadAll(cached)
+				cached.Close()
+				if err == nil {
+					f.setInventory(name, b, r, sidecarName, sidecar)
+					return newMemFile(name, b, r.ModTime), nil
+				}
+			}
+		}
+	}
+	file, err := f.fsys.OpenFile(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	b, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	f.cacheInventory(ctx, name, b, info.ModTime())
+	return newMemFile(name, b, info.ModTime()), nil
+}
+
+// cacheInventory adds the inventory to the cache if it matches the digest in
+// its sidecar.
+func (f *FS) cacheInventory(ctx context.Context, name string, b []byte, modTime time.Time) {
+	var inv inventory
+	if err := json.Unmarshal(b, &inv); err != nil {
+		return
+	}
+	alg, err := digest.DefaultRegistry().Get(inv.DigestAlgorithm)
+	if err != nil {
+		return
+	}
+	digester := alg.Digester()
+	digester.Write(b)
+	r := ref{Alg: alg.ID(), Digest: digester.String(), ModTime: modTime}
+	sidecarName := name + "." + r.Alg
+	sidecar, err := ocflfs.ReadAll(ctx, f.fsys, sidecarName)
+	if err != nil || !strings.EqualFold(sidecarDigest(sidecar), r.Digest) {
+		return
+	}
+	if err := f.cache.putBytes(b, r.Alg, r.Digest); err != nil {
+		return
+	}
+	if err := f.cache.setRef(f.location+"/"+name, r); err != nil {
+		return
+	}
+	f.setInventory(name, b, r, sidecarName, sidecar)
+}
+
+// setInventory records the validated sidecar and the digests of content files
+// listed in the inventory's manifest.
+func (f *FS) setInventory(name string, b []byte, r ref, sidecarName string, sidecar []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sidecars[sidecarName] = sidecar
+	objDir := path.Dir(name)
+	if versionDir.MatchString(path.Base(objDir)) {
+		// version inventories have the same content as the root inventory.
+		return
+	}
+	var inv inventory
+	if err := json.Unmarshal(b, &inv); err != nil {
+		return
+	}
+	for digest, paths := range inv.Manifest {
+		if !validDigest(digest) {
+			continue
+		}
+		for _, p := range paths {
+			f.content[path.Join(objDir, p)] = ref{Alg: r.Alg, Digest: digest}
+		}
+	}
+}
+
+// openContent opens a content file with a known digest.
+func (f *FS) openContent(ctx context.Context, name string, r ref) (fs.File, error) {
+	if cached, err := f.cache.open(r.Alg, r.Digest); err == nil {
+		return &cachedFile{File: cached, name: path.Base(name)}, nil
+	}
+	file, err := f.fsys.OpenFile(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	alg, err := digest.DefaultRegistry().Get(r.Alg)
+	if err != nil {
+		return file, nil
+	}
+	return &teeFile{File: file, cache: f.cache, ref: r, digester: alg.Digester()}, nil
+}
+
+// reset clears digests and sidecars read from inventories, which may be
+// changed by writes.
+func (f *FS) reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	clear(f.content)
+	clear(f.sidecars)
+}
+
+func (f *WriteFS) Write(ctx context.Context, name string, r io.Reader) (int64, error) {
+	f.reset()
+	return ocflfs.Write(ctx, f.fsys, name, r)
+}
+
+func (f *WriteFS) Remove(ctx context.Context, name string) error {
+	f.reset()
+	return ocflfs.Remove(ctx, f.fsys, name)
+}
+
+func (f *WriteFS) RemoveAll(ctx context.Context, name string) error {
+	f.reset()
+	return ocflfs.RemoveAll(ctx, f.fsys, name)
+}
+
+func (f *WriteFS) Copy(ctx context.Context, dst string, src string) (int64, error) {
+	f.reset()
+	return ocflfs.Copy(ctx, f.fsys, dst, f.fsys, src)
+}
+
+// inventory includes the inventory fields used by the cache.
+type inventory struct {
+	DigestAlgorithm string              `json:"digestAlgorithm"`
+	Manifest        map[string][]string `json:"manifest"`
+}
+
+// sidecarDigest returns the digest value from an inventory sidecar file.
+func sidecarDigest(b []byte) string {
+	fields := strings.Fields(string(b))
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// cachedFile is a file in the cache, opened with a content file's name.
+type cachedFile struct {
+	*os.File
+	name string
+}
+
+func (f *cachedFile) Stat() (fs.FileInfo, error) {
+	info, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return namedInfo{FileInfo: info, name: f.name}, nil
+}
+
+// teeFile is a content file from the backend that is added to the cache if
+// it is read completely and its digest matches.
+type teeFile struct {
+	fs.File
+	cache    *Cache
+	ref      ref
+	digester digest.Digester
+	tmp      *os.File // created on the first read
+	failed   bool     // file can't be cached
+	done     bool     // EOF was read
+}
+
+func (f *teeFile) Read(p []byte) (int, error) {
+	if f.tmp == nil && !f.failed {
+		tmp, err := f.cache.tempFile()
+		if err != nil {
+			f.failed = true
+		}
+		f.tmp = tmp
+	}
+	n, err := f.File.Read(p)
+	if n > 0 && !f.failed {
+		f.digester.Write(p[:n])
+		if _, werr := f.tmp.Write(p[:n]); werr != nil {
+			f.failed = true
+		}
+	}
+	if errors.Is(err, io.EOF) {
+		f.done = true
+	}
+	return n, err
+}
+
+func (f *teeFile) Close() error {
+	err := f.File.Close()
+	if f.tmp == nil {
+		return err
+	}
+	tmpName := f.tmp.Name()
+	closeErr := f.tmp.Close()
+	if f.failed || !f.done || closeErr != nil || !strings.EqualFold(f.digester.String(), f.ref.Digest) {
+		os.Remove(tmpName)
+		return err
+	}
+	if putErr := f.cache.put(tmpName, f.ref.Alg, f.ref.Digest); putErr != nil {
+		os.Remove(tmpName)
+	}
+	return err
+}
+
+// memFile is a file read into memory
+type memFile struct {
+	*bytes.Reader
+	info memInfo
+}
+
+func newMemFile(name string, b []byte, modTime time.Time) *memFile {
+	return &memFile{
+		Reader: bytes.NewReader(b),
+		info:   memInfo{name: path.Base(name), size: int64(len(b)), modTime: modTime},
+	}
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memFile) Close() error               { return nil }
+
+type memInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (i memInfo) Name() string       { return i.name }
+func (i memInfo) Size() int64        { return i.size }
+func (i memInfo) Mode() fs.FileMode  { return 0o444 }
+func (i memInfo) ModTime() time.Time { return i.modTime }
+func (i memInfo) IsDir() bool        { return false }
+func (i memInfo) Sys() any           { return nil }
+
+type namedInfo struct {
+	fs.FileInfo
+	name string
+}
+
+func (i namedInfo) Name() string { return i.name }
@@ -2,6 +2,7 @@ package diff
 
 import (
 	"fmt"
+	"io"
 	"sort"
 	"strings"
 
@@ -9,21 +10,89 @@ import (
 	"golang.org/x/exp/maps"
 )
 
-var (
-	addStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
-	remStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
-	modStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
-	movStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("14"))
-)
+var defaultStyles = newStyles(lipgloss.DefaultRenderer(), true)
+
+// styles are used to color diff output.
+type styles struct {
+	add, rem, mod, mov, header, hunk lipgloss.Style
+}
+
+func newStyles(r *lipgloss.Renderer, color bool) styles {
+	// tabs are preserved in patch lines
+	base := r.NewStyle().TabWidth(lipgloss.NoTabConversion)
+	if !color {
+		return styles{add: base, rem: base, mod: base, mov: base, header: base, hunk: base}
+	}
+	return styles{
+		add:    base.Foreground(lipgloss.Color("10")),
+		rem:    base.Foreground(lipgloss.Color("9")),
+		mod:    base.Foreground(lipgloss.Color("11")),
+		mov:    base.Foreground(lipgloss.Color("14")),
+		header: base.Bold(true),
+		hunk:   base.Foreground(lipgloss.Color("14")),
+	}
+}
+
+// Printer writes diff results and patches to an io.Writer. Colors are only
+// used if the writer is a terminal.
+type Printer struct {
+	w      io.Writer
+	styles styles
+}
+
+// NewPrinter returns a Printer that writes to w. If color is false, colors
+// are never used.
+func NewPrinter(w io.Writer, color bool) *Printer {
+	return &Printer{w: w, styles: newStyles(lipgloss.NewRenderer(w), color)}
+}
+
+// Result writes the changes in r.
+func (p *Printer) Result(r Result) {
+	r.write(p.w, p.styles)
+}
+
+// Patch writes a unified diff for a single file (see [Unified]) with colors
+// for added and removed lines.
+func (p *Printer) Patch(patch string) {
+	for i, line := range strings.SplitAfter(patch, "\n") {
+		text := strings.TrimSuffix(line, "\n")
+		var style lipgloss.Style
+		switch {
+		case i < 2 && (strings.HasPrefix(text, "--- ") || strings.HasPrefix(text, "+++ ")):
+			style = p.styles.header
+		case strings.HasPrefix(text, "@@"):
+			style = p.styles.hunk
+		case strings.HasPrefix(text, "+"):
+			style = p.styles.add
+		case strings.HasPrefix(text, "-"):
+			style = p.styles.rem
+		default:
+			fmt.Fprint(p.w, line)
+			continue
+		}
+		fmt.Fprint(p.w, style.Render(text), line[len(text):])
+	}
+}
 
 type Result struct {
-	Added    []string
-	Removed  []string
-	Modified []string
-	Renamed  map[string]string
+	Added    []string          `json:"added"`
+	Removed  []string          `json:"removed"`
+	Modified []string          `json:"modified"`
+	Renamed  map[string]string `json:"renamed"`
+	// RenamedModified are files that were renamed and modified, found using
+	// [WithSimilarity].
+	RenamedModified map[string]string `json:"renamed_modified"`
 }
 
-func Diff(aPaths, bPaths map[string]string) (result Result, err error) {
+// Diff returns the changes from aPaths to bPaths, which map logical paths to
+// digests. By default, files with the same digest in added and removed paths
+// are renamed, pairing duplicate digests by sort order. See [Option] for ways
+// to customize rename detection.
+func Diff(aPaths, bPaths map[string]string, opts ...Option) (result Result, err error) {
+	var conf config
+	for _, o := range opts {
+		o(&conf)
+	}
 	addMap := map[string][]string{} // digest map of new files in b
 	rmMap := map[string][]string{}  // digest map of missing files in b
 	for aPath, aDigest := range aPaths {
@@ -49,6 +118,12 @@ func Diff(aPaths, bPaths map[string]string) (result Result, err error) {
 		rmPaths := rmMap[dig]
 		sort.Strings(addPaths) // sort to make result deterministic
 		sort.Strings(rmPaths)
+		if conf.dirRenames {
+			// pair paths with the same base name (and parent directories)
+			var pairs map[string]string
+			pairs, rmPaths, addPaths = pairBySuffix(rmPaths, addPaths)
+			maps.Copy(renamed, pairs)
+		}
 		switch {
 		case len(addPaths) > len(rmPaths):
 			// create a rename pair for each rmPath
@@ -79,31 +154,49 @@ func Diff(aPaths, bPaths map[string]string) (result Result, err error) {
 	sort.Strings(result.Added)
 	sort.Strings(result.Removed)
 	sort.Strings(result.Modified)
+	if conf.similarity != nil {
+		if err = result.findRenamedModified(conf.similarity, conf.threshold); err != nil {
+			return
+		}
+	}
+	if conf.dirRenames {
+		result.collapseDirRenames(aPaths, bPaths)
+	}
 	return
 }
 
 func (r Result) String() string {
 	b := &strings.Builder{}
+	r.write(b, defaultStyles)
+	return b.String()
+}
+
+func (r Result) write(w io.Writer, s styles) {
 	for _, n := range r.Added {
-		fmt.Fprintln(b, addStyle.Render("add:"), n)
+		fmt.Fprintln(w, s.add.Render("add:"), n)
 	}
 	for _, n := range r.Removed {
-		fmt.Fprintln(b, remStyle.Render("rem:"), n)
+		fmt.Fprintln(w, s.rem.Render("rem:"), n)
 	}
 	for _, n := range r.Modified {
-		fmt.Fprintln(b, modStyle.Render("mod:"), n)
+		fmt.Fprintln(w, s.mod.Render("mod:"), n)
 	}
 	moved := maps.Keys(r.Renamed)
 	sort.Strings(moved)
 	for _, n := range moved {
-		fmt.Fprintln(b, movStyle.Render("mov:"), "{", n, "=>", r.Renamed[n], "}")
+		fmt.Fprintln(w, s.mov.Render("mov:"), "{", n, "=>", r.Renamed[n], "}")
+	}
+	moved = maps.Keys(r.RenamedModified)
+	sort.Strings(moved)
+	for _, n := range moved {
+		fmt.Fprintln(w, s.mov.Render("mov:"), "{", n, "=>", r.RenamedModified[n], "}", s.mod.Render("(modified)"))
 	}
-	return b.String()
 }
 
 func (diff Result) Empty() bool {
 	return len(diff.Added) == 0 &&
 		len(diff.Removed) == 0 &&
 		len(diff.Modified) == 0 &&
-		len(diff.Renamed) == 0
+		len(diff.Renamed) == 0 &&
+		len(diff.RenamedModified) == 0
 }
@@ -0,0 +1,272 @@
+package diff
+
+import (
+	"cmp"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Option is used to configure rename detection in [Diff]
+type Option func(*config)
+
+type config struct {
+	dirRenames bool
+	similarity SimilarityFunc
+	threshold  float64
+}
+
+// SimilarityFunc returns the similarity of the content of file aPath in the
+// first set of paths and file bPath in the second, from 0 (different) to 1
+// (the same).
+type SimilarityFunc func(aPath, bPath string) (float64, error)
+
+// WithDirRenames is an option for [Diff] that detects renamed directories.
+// Renamed files with duplicate digests are paired by preferring paths with the
+// same base name and parent directory names. If every file in a directory is
+// renamed to the same new directory, the file renames are replaced with a
+// single rename of the directory, with keys and values ending in '/'. Renamed
+// and modified files (see [WithSimilarity]) may be part of renamed
+// directories but are still included in RenamedModified.
+func WithDirRenames() Option {
+	return func(c *config) {
+		c.dirRenames = true
+	}
+}
+
+// WithSimilarity is an option for [Diff] that detects files that were renamed
+// and modified. Removed and added files with the same base name are compared
+// using fn, and paired if their similarity is at least threshold.
+func WithSimilarity(threshold float64, fn SimilarityFunc) Option {
+	return func(c *config) {
+		c.similarity = fn
+		c.threshold = threshold
+	}
+}
+
+// Similarity returns the proportion of lines in a and b that are unchanged,
+// from 0 to 1.
+func Similarity(a, b []byte) float64 {
+	aLines, bLines := splitLines(a), splitLines(b)
+	if len(aLines)+len(bLines) == 0 {
+		return 1
+	}
+	var same int
+	for _, op := range lineOps(aLines, bLines) {
+		if op.kind == opEqual {
+			same++
+		}
+	}
+	return float64(2*same) / float64(len(aLines)+len(bLines))
+}
+
+// pairBySuffix pairs paths in rmPaths and addPaths that share the most
+// trailing path elements (at least the base name). Paths in both slices must
+// be sorted. It returns the pairs and the sorted, unpaired paths.
+func pairBySuffix(rmPaths, addPaths []string) (map[string]string, []string, []string) {
+	pairs := map[string]string{}
+	if len(rmPaths) == 0 || len(addPaths) == 0 {
+		return pairs, rmPaths, addPaths
+	}
+	var maxDepth int
+	for _, p := range rmPaths {
+		maxDepth = max(maxDepth, strings.Count(p, "/")+1)
+	}
+	pairedAdd := map[string]bool{}
+	for depth := maxDepth; depth > 0; depth-- {
+		unpaired := map[string][]string{} // unpaired rmPaths by suffix
+		for _, p := range rmPaths {
+			if _, paired := pairs[p]; paired {
+				continue
+			}
+			if suffix, ok := pathSuffix(p, depth); ok {
+				unpaired[suffix] = append(unpaired[suffix], p)
+			}
+		}
+		for _, p := range addPaths {
+			if pairedAdd[p] {
+				continue
+			}
+			suffix, ok := pathSuffix(p, depth)
+			if !ok || len(unpaired[suffix]) == 0 {
+				continue
+			}
+			pairs[unpaired[suffix][0]] = p
+			unpaired[suffix] = unpaired[suffix][1:]
+			pairedAdd[p] = true
+		}
+	}
+	var rmRest, addRest []string
+	for _, p := range rmPaths {
+		if _, paired := pairs[p]; !paired {
+			rmRest = append(rmRest, p)
+		}
+	}
+	for _, p := range addPaths {
+		if !pairedAdd[p] {
+			addRest = append(addRest, p)
+		}
+	}
+	return pairs, rmRest, addRest
+}
+
+// pathSuffix returns the last n elements of name. It returns false if name
+// has fewer than n elements.
+func pathSuffix(name string, n int) (string, bool) {
+	i := len(name)
+	for range n {
+		if i < 0 {
+			return "", false
+		}
+		i = strings.LastIndex(name[:i], "/")
+	}
+	return name[i+1:], true
+}
+
+// findRenamedModified moves pairs of removed and added files with the same
+// base name and a similarity of at least threshold to RenamedModified.
+func (r *Result) findRenamedModified(similarity SimilarityFunc, threshold float64) error {
+	// limit on the number of comparisons for files with the same base name
+	const maxCompare = 1000
+	type candidate struct {
+		rm, add string
+		score   float64
+	}
+	added := map[string][]string{} // added paths by base name
+	for _, p := range r.Added {
+		added[path.Base(p)] = append(added[path.Base(p)], p)
+	}
+	removed := map[string][]string{} // removed paths by base name
+	for _, p := range r.Removed {
+		removed[path.Base(p)] = append(removed[path.Base(p)], p)
+	}
+	var candidates []candidate
+	for base, rmPaths := range removed {
+		addPaths := added[base]
+		if len(addPaths) == 0 || len(rmPaths)*len(addPaths) > maxCompare {
+			continue
+		}
+		for _, rm := range rmPaths {
+			for _, add := range addPaths {
+				score, err := similarity(rm, add)
+				if err != nil {
+					return err
+				}
+				if score >= threshold {
+					candidates = append(candidates, candidate{rm: rm, add: add, score: score})
+				}
+			}
+		}
+	}
+	// best matches first
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		return cmp.Or(
+			cmp.Compare(b.score, a.score),
+			cmp.Compare(a.rm, b.rm),
+			cmp.Compare(a.add, b.add),
+		)
+	})
+	pairedAdd := map[string]bool{}
+	for _, c := range candidates {
+		if _, paired := r.RenamedModified[c.rm]; paired || pairedAdd[c.add] {
+			continue
+		}
+		if r.RenamedModified == nil {
+			r.RenamedModified = map[string]string{}
+		}
+		r.RenamedModified[c.rm] = c.add
+		pairedAdd[c.add] = true
+	}
+	r.Added = slices.DeleteFunc(r.Added, func(p string) bool { return pairedAdd[p] })
+	r.Removed = slices.DeleteFunc(r.Removed, func(p string) bool {
+		_, paired := r.RenamedModified[p]
+		return paired
+	})
+	return nil
+}
+
+// collapseDirRenames replaces renames of all files in a directory with a
+// rename of the directory. A directory is renamed if every file in it was
+// renamed (or renamed and modified) to the same relative path in a new
+// directory that doesn't exist in aPaths, and the directory doesn't exist in
+// bPaths.
+func (r *Result) collapseDirRenames(aPaths, bPaths map[string]string) {
+	aDirs := dirCounts(aPaths)
+	bDirs := dirCounts(bPaths)
+	type dirRename struct {
+		dst   string
+		count int
+		ok    bool
+	}
+	dirs := map[string]*dirRename{}
+	addRename := func(src, dst string) {
+		for dir := path.Dir(src); dir != "."; dir = path.Dir(dir) {
+			rel := src[len(dir):] // starts with "/"
+			dstDir, found := strings.CutSuffix(dst, rel)
+			d := dirs[dir]
+			if d == nil {
+				d = &dirRename{dst: dstDir, ok: found && dstDir != ""}
+				dirs[dir] = d
+			}
+			if !found || dstDir != d.dst {
+				d.ok = false
+			}
+			d.count++
+		}
+	}
+	for src, dst := range r.Renamed {
+		addRename(src, dst)
+	}
+	for src, dst := range r.RenamedModified {
+		addRename(src, dst)
+	}
+	// shallowest directories first
+	sorted := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		sorted = append(sorted, dir)
+	}
+	slices.SortFunc(sorted, func(a, b string) int {
+		return cmp.Or(cmp.Compare(strings.Count(a, "/"), strings.Count(b, "/")), cmp.Compare(a, b))
+	})
+	collapsed := map[string]bool{}
+	for _, dir := range sorted {
+		d := dirs[dir]
+		if !d.ok || d.count != aDirs[dir] || bDirs[dir] > 0 || aDirs[d.dst] > 0 {
+			continue
+		}
+		if hasCollapsedParent(dir, collapsed) {
+			continue
+		}
+		collapsed[dir] = true
+		for src := range r.Renamed {
+			if strings.HasPrefix(src, dir+"/") {
+				delete(r.Renamed, src)
+			}
+		}
+		if r.Renamed == nil {
+			r.Renamed = map[string]string{}
+		}
+		r.Renamed[dir+"/"] = d.dst + "/"
+	}
+}
+
+func hasCollapsedParent(dir string, collapsed map[string]bool) bool {
+	for parent := path.Dir(dir); parent != "."; parent = path.Dir(parent) {
+		if collapsed[parent] {
+			return true
+		}
+	}
+	return false
+}
+
+// dirCounts returns the number of paths in each directory, including
+// subdirectories.
+func dirCounts(paths map[string]string) map[string]int {
+	counts := map[string]int{}
+	for name := range paths {
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			counts[dir]++
+		}
+	}
+	return counts
+}
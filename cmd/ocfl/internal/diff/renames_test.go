@@ -0,0 +1,80 @@
+package diff_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/diff"
+)
+
+func TestDiffDirRenames(t *testing.T) {
+	a := map[string]string{"keep.txt": "k"}
+	b := map[string]string{"keep.txt": "k"}
+	for i := range 100 {
+		a[fmt.Sprintf("data/old/%d.txt", i)] = fmt.Sprint(i)
+		b[fmt.Sprintf("data/new/%d.txt", i)] = fmt.Sprint(i)
+	}
+	// duplicate digests
+	a["data/old/sub/x/empty"] = "e"
+	a["data/old/sub/y/empty"] = "e"
+	b["data/new/sub/x/empty"] = "e"
+	b["data/new/sub/y/empty"] = "e"
+	t.Run("default", func(t *testing.T) {
+		result, err := diff.Diff(a, b)
+		be.NilErr(t, err)
+		be.Equal(t, 102, len(result.Renamed))
+	})
+	t.Run("with dir renames", func(t *testing.T) {
+		result, err := diff.Diff(a, b, diff.WithDirRenames())
+		be.NilErr(t, err)
+		be.DeepEqual(t, map[string]string{"data/old/": "data/new/"}, result.Renamed)
+		be.Equal(t, "mov: { data/old/ => data/new/ }\n", result.String())
+	})
+	t.Run("not all files renamed", func(t *testing.T) {
+		b2 := map[string]string{"data/old/0.txt": "changed"}
+		for name, dig := range b {
+			b2[name] = dig
+		}
+		delete(b2, "data/new/0.txt")
+		result, err := diff.Diff(a, b2, diff.WithDirRenames())
+		be.NilErr(t, err)
+		be.Equal(t, "data/new/sub/", result.Renamed["data/old/sub/"])
+		be.Equal(t, "data/new/1.txt", result.Renamed["data/old/1.txt"])
+		be.AllEqual(t, []string{"data/old/0.txt"}, result.Modified)
+	})
+}
+
+func TestDiffBaseNamePreference(t *testing.T) {
+	a := map[string]string{"a/readme.txt": "d", "a/notes.txt": "d"}
+	b := map[string]string{"b/notes.txt": "d", "c/readme.txt": "d", "a/other": "o"}
+	result, err := diff.Diff(a, b)
+	be.NilErr(t, err)
+	be.Equal(t, "b/notes.txt", result.Renamed["a/notes.txt"])
+	be.Equal(t, "c/readme.txt", result.Renamed["a/readme.txt"])
+	b = map[string]string{"b/readme.txt": "d", "c/notes.txt": "d", "a/other": "o"}
+	result, err = diff.Diff(a, b, diff.WithDirRenames())
+	be.NilErr(t, err)
+	be.Equal(t, "c/notes.txt", result.Renamed["a/notes.txt"])
+	be.Equal(t, "b/readme.txt", result.Renamed["a/readme.txt"])
+}
+
+func TestDiffWithSimilarity(t *testing.T) {
+	content := map[string]string{
+		"a/file.txt": "1\n2\n3\n4\n",
+		"b/file.txt": "1\n2\n3\nfour\n",
+		"a/gone.txt": "1\n2\n",
+		"c/gone.txt": "3\n4\n",
+	}
+	a := map[string]string{"a/file.txt": "x", "a/gone.txt": "y"}
+	b := map[string]string{"b/file.txt": "z", "c/gone.txt": "w"}
+	similarity := func(aPath, bPath string) (float64, error) {
+		return diff.Similarity([]byte(content[aPath]), []byte(content[bPath])), nil
+	}
+	result, err := diff.Diff(a, b, diff.WithSimilarity(0.5, similarity))
+	be.NilErr(t, err)
+	be.DeepEqual(t, map[string]string{"a/file.txt": "b/file.txt"}, result.RenamedModified)
+	be.AllEqual(t, []string{"c/gone.txt"}, result.Added)
+	be.AllEqual(t, []string{"a/gone.txt"}, result.Removed)
+	be.In(t, "mov: { a/file.txt => b/file.txt } (modified)", result.String())
+}
@@ -0,0 +1,240 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// maxEdits is the maximum number of line edits considered by [Unified] when
+// searching for the shortest edit script. Files with more differences are
+// shown as a replacement of all lines after their common prefix and suffix.
+const maxEdits = 1000
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+// lineOp is an edit script operation. a and b are the indexes of the lines
+// in the old and new files.
+type lineOp struct {
+	kind opKind
+	a, b int
+}
+
+// Unified returns a unified diff of the contents of the old file a and the new
+// file b with the given number of context lines. The result is empty if the
+// contents are the same.
+func Unified(aName, bName string, a, b []byte, context int) string {
+	aLines, bLines := splitLines(a), splitLines(b)
+	ops := lineOps(aLines, bLines)
+	buf := &strings.Builder{}
+	for _, hunk := range hunks(ops, context) {
+		if buf.Len() == 0 {
+			fmt.Fprintf(buf, "--- %s\n+++ %s\n", aName, bName)
+		}
+		var aCount, bCount int
+		for _, op := range hunk {
+			if op.kind != opInsert {
+				aCount++
+			}
+			if op.kind != opDelete {
+				bCount++
+			}
+		}
+		aStart, bStart := hunk[0].a, hunk[0].b
+		if aCount > 0 {
+			aStart++
+		}
+		if bCount > 0 {
+			bStart++
+		}
+		fmt.Fprintf(buf, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+		for _, op := range hunk {
+			switch op.kind {
+			case opEqual:
+				writeLine(buf, " ", aLines[op.a])
+			case opDelete:
+				writeLine(buf, "-", aLines[op.a])
+			case opInsert:
+				writeLine(buf, "+", bLines[op.b])
+			}
+		}
+	}
+	return buf.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func writeLine(buf *strings.Builder, prefix string, line string) {
+	buf.WriteString(prefix)
+	buf.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		buf.WriteString("\n\\ No newline at end of file\n")
+	}
+}
+
+// splitLines splits data into lines, each including its trailing newline.
+func splitLines(data []byte) []string {
+	var lines []string
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			lines = append(lines, string(data))
+			break
+		}
+		lines = append(lines, string(data[:i+1]))
+		data = data[i+1:]
+	}
+	return lines
+}
+
+// hunks groups the operations in ops into hunks with changes separated by no
+// more than 2*context unchanged lines.
+func hunks(ops []lineOp, context int) [][]lineOp {
+	var result [][]lineOp
+	start, last := -1, -1 // first op in the current hunk and its last change
+	for i, op := range ops {
+		if op.kind == opEqual {
+			continue
+		}
+		if start >= 0 && i-last-1 > 2*context {
+			result = append(result, ops[start:min(len(ops), last+context+1)])
+			start = -1
+		}
+		if start < 0 {
+			start = max(0, i-context)
+		}
+		last = i
+	}
+	if start >= 0 {
+		result = append(result, ops[start:min(len(ops), last+context+1)])
+	}
+	return result
+}
+
+// lineOps returns an edit script that converts a to b. The common prefix and
+// suffix are removed before searching for the shortest edit script in the
+// remaining lines.
+func lineOps(a, b []string) []lineOp {
+	var prefix, suffix int
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	var ops []lineOp
+	for i := range prefix {
+		ops = append(ops, lineOp{kind: opEqual, a: i, b: i})
+	}
+	aMid, bMid := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	for _, op := range myers(aMid, bMid) {
+		op.a += prefix
+		op.b += prefix
+		ops = append(ops, op)
+	}
+	for i := range suffix {
+		ops = append(ops, lineOp{kind: opEqual, a: len(a) - suffix + i, b: len(b) - suffix + i})
+	}
+	return ops
+}
+
+// myers returns the shortest edit script that converts a to b using Myers'
+// algorithm. If the script would have more than maxEdits changes, all lines in
+// a are deleted and all lines in b are inserted.
+func myers(a, b []string) []lineOp {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return replaceAll(n, m)
+	}
+	// trace[d] holds the furthest x reached on diagonals -d..d after d edits,
+	// stored at index k+d.
+	var trace [][]int
+	prev := []int{0} // diagonal k=1 before the first step, indexed k-1
+	prevOffset := -1
+	for d := 0; d <= min(n+m, maxEdits); d++ {
+		v := make([]int, 2*d+1)
+		get := func(k int) int { return prev[k+prevOffset] }
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && get(k-1) < get(k+1)) {
+				x = get(k + 1)
+			} else {
+				x = get(k-1) + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[k+d] = x
+			if x >= n && y >= m {
+				trace = append(trace, v)
+				return backtrack(trace, n, m)
+			}
+		}
+		trace = append(trace, v)
+		prev, prevOffset = v, d
+	}
+	return replaceAll(n, m)
+}
+
+// backtrack builds the edit script from the trace of a completed search.
+func backtrack(trace [][]int, n, m int) []lineOp {
+	var ops []lineOp
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1]
+		get := func(k int) int { return prev[k+d-1] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && get(k-1) < get(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := get(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, lineOp{kind: opEqual, a: x, b: y})
+		}
+		if x == prevX {
+			ops = append(ops, lineOp{kind: opInsert, a: x, b: prevY})
+		} else {
+			ops = append(ops, lineOp{kind: opDelete, a: prevX, b: prevY})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, lineOp{kind: opEqual, a: x, b: y})
+	}
+	slices.Reverse(ops)
+	return ops
+}
+
+func replaceAll(n, m int) []lineOp {
+	ops := make([]lineOp, 0, n+m)
+	for i := range n {
+		ops = append(ops, lineOp{kind: opDelete, a: i, b: 0})
+	}
+	for j := range m {
+		ops = append(ops, lineOp{kind: opInsert, a: n, b: j})
+	}
+	return ops
+}
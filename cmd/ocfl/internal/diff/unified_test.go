@@ -0,0 +1,26 @@
+package diff_test
+
+import (
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/diff"
+)
+
+func TestUnified(t *testing.T) {
+	t.Run("same", func(t *testing.T) {
+		be.Equal(t, "", diff.Unified("a", "b", []byte("1\n2\n"), []byte("1\n2\n"), 3))
+	})
+	t.Run("hunks", func(t *testing.T) {
+		a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+		b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n12\n13"
+		expect := "--- a\n+++ b\n" +
+			"@@ -1,5 +1,5 @@\n 1\n 2\n-3\n+three\n 4\n 5\n" +
+			"@@ -9,4 +9,4 @@\n 9\n 10\n-11\n 12\n+13\n\\ No newline at end of file\n"
+		be.Equal(t, expect, diff.Unified("a", "b", []byte(a), []byte(b), 2))
+	})
+	t.Run("empty file", func(t *testing.T) {
+		expect := "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+1\n+2\n"
+		be.Equal(t, expect, diff.Unified("a", "b", nil, []byte("1\n2\n"), 3))
+	})
+}
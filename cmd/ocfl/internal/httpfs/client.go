@@ -0,0 +1,199 @@
+package httpfs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRetryWait = 500 * time.Millisecond
+	maxRetryWait     = 30 * time.Second
+)
+
+// ClientConfig configures the http.Client used to access a storage root or
+// object.
+type ClientConfig struct {
+	// Header is added to requests to the base URL's host.
+	Header http.Header
+	// Username and Password are used for basic authentication with the base
+	// URL's host.
+	Username string
+	Password string
+	// Token is used for bearer token authentication with the base URL's host.
+	// It takes precedence over Username and Password.
+	Token string
+	// NetrcFile is a .netrc file with credentials used if Token and Username
+	// aren't set.
+	NetrcFile string
+	// CAFile is a PEM file with certificate authorities used to verify the
+	// server (in addition to the system's).
+	CAFile string
+	// CertFile and KeyFile are PEM files with the client certificate and key.
+	CertFile string
+	KeyFile  string
+	// Timeout is the time to wait for a response's headers. Zero means no
+	// timeout.
+	Timeout time.Duration
+	// Retries is the number of times requests that fail with a temporary
+	// error status (502, 503, 504, or 429 with Retry-After) are retried.
+	Retries int
+	// RetryWait is the delay before the first retry; it doubles with each
+	// retry. Defaults to 500ms.
+	RetryWait time.Duration
+}
+
+// NewClient returns an http.Client for accessing content under baseURL.
+// Credentials and headers from the config are only sent to the base URL's
+// host.
+func NewClient(baseURL string, cfg ClientConfig) (*http.Client, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = cfg.Timeout
+	if cfg.CAFile != "" || cfg.CertFile != "" || cfg.KeyFile != "" {
+		tlsConfig, err := cfg.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	rt := &roundTripper{
+		next:      transport,
+		host:      base.Host,
+		header:    cfg.Header,
+		token:     cfg.Token,
+		username:  cfg.Username,
+		password:  cfg.Password,
+		retries:   cfg.Retries,
+		retryWait: cfg.RetryWait,
+	}
+	if rt.retryWait <= 0 {
+		rt.retryWait = defaultRetryWait
+	}
+	if rt.token == "" && rt.username == "" && cfg.NetrcFile != "" {
+		login, err := readNetrc(cfg.NetrcFile, base.Hostname())
+		if err != nil {
+			return nil, err
+		}
+		rt.username, rt.password = login.login, login.password
+	}
+	return &http.Client{Transport: rt}, nil
+}
+
+func (cfg ClientConfig) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.CAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA file: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file: %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		if cfg.CertFile == "" || cfg.KeyFile == "" {
+			return nil, errors.New("client certificate requires both a certificate file and a key file")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// roundTripper adds headers and credentials to requests and retries requests
+// that fail with a temporary error status.
+type roundTripper struct {
+	next      http.RoundTripper
+	host      string
+	header    http.Header
+	token     string
+	username  string
+	password  string
+	retries   int
+	retryWait time.Duration
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == rt.host {
+		req = req.Clone(req.Context())
+		for k, vals := range rt.header {
+			req.Header[k] = vals
+		}
+		switch {
+		case rt.token != "":
+			req.Header.Set("Authorization", "Bearer "+rt.token)
+		case rt.username != "":
+			req.SetBasicAuth(rt.username, rt.password)
+		}
+	}
+	// requests with bodies can't be retried unless they can be reset.
+	retries := rt.retries
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		retries = 0
+	}
+	wait := rt.retryWait
+	for attempt := 0; ; attempt++ {
+		resp, err := rt.next.RoundTrip(req)
+		if err != nil || !retryable(resp) || attempt >= retries {
+			return resp, err
+		}
+		delay := retryAfter(resp, wait)
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		wait = min(2*wait, maxRetryWait)
+		if req.GetBody != nil {
+			req = req.Clone(req.Context())
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// retryable returns true if the response's status is for a temporary error.
+// Other errors, like 500 or 501, aren't likely to be resolved by retrying.
+func retryable(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	case http.StatusTooManyRequests:
+		return resp.Header.Get("Retry-After") != ""
+	}
+	return false
+}
+
+// retryAfter returns the delay from the response's Retry-After header or
+// wait, with jitter.
+func retryAfter(resp *http.Response, wait time.Duration) time.Duration {
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+		return min(time.Duration(secs)*time.Second, maxRetryWait)
+	}
+	return wait/2 + rand.N(wait/2+1)
+}
@@ -0,0 +1,138 @@
+package httpfs_test
+
+import (
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/httpfs"
+)
+
+func TestNewClient(t *testing.T) {
+	// echo returns the request's Authorization and X-Test headers.
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Header.Get("Authorization")+"|"+r.Header.Get("X-Test"))
+	})
+	t.Run("token and headers", func(t *testing.T) {
+		srv := httptest.NewServer(echo)
+		defer srv.Close()
+		cli, err := httpfs.NewClient(srv.URL, httpfs.ClientConfig{
+			Token:  "s3cr3t",
+			Header: http.Header{"X-Test": {"value"}},
+		})
+		be.NilErr(t, err)
+		be.Equal(t, "Bearer s3cr3t|value", get(t, cli, srv.URL))
+	})
+	t.Run("credentials only sent to base host", func(t *testing.T) {
+		other := httptest.NewServer(echo)
+		defer other.Close()
+		srv := httptest.NewServer(http.RedirectHandler(other.URL, http.StatusFound))
+		defer srv.Close()
+		cli, err := httpfs.NewClient(srv.URL, httpfs.ClientConfig{
+			Token:  "s3cr3t",
+			Header: http.Header{"X-Test": {"value"}},
+		})
+		be.NilErr(t, err)
+		be.Equal(t, "|", get(t, cli, srv.URL))
+	})
+	t.Run("netrc", func(t *testing.T) {
+		srv := httptest.NewServer(echo)
+		defer srv.Close()
+		netrc := filepath.Join(t.TempDir(), ".netrc")
+		be.NilErr(t, os.WriteFile(netrc, []byte(
+			"machine example.com login other password other\n"+
+				"machine 127.0.0.1\n  login user\n  password pass\n"+
+				"default login anon password anon\n",
+		), 0600))
+		cli, err := httpfs.NewClient(srv.URL, httpfs.ClientConfig{NetrcFile: netrc})
+		be.NilErr(t, err)
+		req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+		be.NilErr(t, err)
+		req.SetBasicAuth("user", "pass")
+		be.Equal(t, req.Header.Get("Authorization")+"|", get(t, cli, srv.URL))
+		// missing netrc file is ignored
+		cli, err = httpfs.NewClient(srv.URL, httpfs.ClientConfig{NetrcFile: netrc + "-missing"})
+		be.NilErr(t, err)
+		be.Equal(t, "|", get(t, cli, srv.URL))
+	})
+	t.Run("retries", func(t *testing.T) {
+		var count atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if count.Add(1) <= 2 {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			io.WriteString(w, "ok")
+		}))
+		defer srv.Close()
+		cli, err := httpfs.NewClient(srv.URL, httpfs.ClientConfig{Retries: 1, RetryWait: time.Millisecond})
+		be.NilErr(t, err)
+		resp, err := cli.Get(srv.URL)
+		be.NilErr(t, err)
+		resp.Body.Close()
+		be.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		count.Store(0)
+		cli, err = httpfs.NewClient(srv.URL, httpfs.ClientConfig{Retries: 2, RetryWait: time.Millisecond})
+		be.NilErr(t, err)
+		be.Equal(t, "ok", get(t, cli, srv.URL))
+		be.Equal(t, 3, count.Load())
+	})
+	t.Run("no retries for other errors", func(t *testing.T) {
+		var count atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			count.Add(1)
+			switch r.URL.Path {
+			case "/throttled":
+				// retried only with Retry-After
+				http.Error(w, "slow down", http.StatusTooManyRequests)
+			default:
+				http.Error(w, "not implemented", http.StatusNotImplemented)
+			}
+		}))
+		defer srv.Close()
+		cli, err := httpfs.NewClient(srv.URL, httpfs.ClientConfig{Retries: 2, RetryWait: time.Millisecond})
+		be.NilErr(t, err)
+		for _, u := range []string{srv.URL, srv.URL + "/throttled"} {
+			count.Store(0)
+			resp, err := cli.Get(u)
+			be.NilErr(t, err)
+			resp.Body.Close()
+			be.Equal(t, 1, count.Load())
+		}
+	})
+	t.Run("ca file", func(t *testing.T) {
+		srv := httptest.NewTLSServer(echo)
+		defer srv.Close()
+		cli, err := httpfs.NewClient(srv.URL, httpfs.ClientConfig{})
+		be.NilErr(t, err)
+		_, err = cli.Get(srv.URL)
+		be.True(t, err != nil)
+		caFile := filepath.Join(t.TempDir(), "ca.pem")
+		caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+		be.NilErr(t, os.WriteFile(caFile, caPEM, 0644))
+		cli, err = httpfs.NewClient(srv.URL, httpfs.ClientConfig{CAFile: caFile, Token: "s3cr3t"})
+		be.NilErr(t, err)
+		be.Equal(t, "Bearer s3cr3t|", get(t, cli, srv.URL))
+		// key file is required with a client certificate
+		_, err = httpfs.NewClient(srv.URL, httpfs.ClientConfig{CertFile: caFile})
+		be.True(t, err != nil)
+	})
+}
+
+func get(t *testing.T, cli *http.Client, u string) string {
+	t.Helper()
+	resp, err := cli.Get(u)
+	be.NilErr(t, err)
+	defer resp.Body.Close()
+	be.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	be.NilErr(t, err)
+	return string(body)
+}
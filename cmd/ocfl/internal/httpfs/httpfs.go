@@ -1,12 +1,33 @@
 package httpfs
 
-import "github.com/srerickson/ocfl-go/fs/http"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"iter"
+	"path"
+	"strings"
+	"time"
+
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-go/fs/http"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/rootindex"
+)
+
+// storage root declarations and configuration files, which are found in
+// storage root directories without listing them.
+var (
+	rootDeclarations = []string{"0=ocfl_1.1", "0=ocfl_1.0"}
+	rootConfigFiles  = []string{"ocfl_layout.json"}
+)
 
 // FS just wraps http.FS an keeps a copy of the URL for reporting (because
 // http.FS doesn't have a way to get the url back out).
 type FS struct {
 	http.FS
 	baseURL string
+	indexes rootindex.Cache // storage root indexes used to list directories
 }
 
 func New(url string, opts ...http.Option) *FS {
@@ -17,3 +38,169 @@ func New(url string, opts ...http.Option) *FS {
 }
 
 func (fs *FS) URL() string { return fs.baseURL }
+
+// OpenFile wraps http.FS's OpenFile, escaping "%" in name. Without it, names
+// with percent-encoded characters (common in object paths) are decoded when
+// the request URL is constructed.
+func (fsys *FS) OpenFile(ctx context.Context, name string) (fs.File, error) {
+	if !strings.Contains(name, "%") {
+		return fsys.FS.OpenFile(ctx, name)
+	}
+	f, err := fsys.FS.OpenFile(ctx, strings.ReplaceAll(name, "%", "%25"))
+	if err != nil {
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			pathErr.Path = name
+		}
+		return nil, err
+	}
+	return &namedFile{File: f, name: path.Base(name)}, nil
+}
+
+// WalkFiles implements ocflfs.FileWalker for storage roots with an index
+// created by 'ocfl index build'. Without an index, the iterator yields an
+// error wrapping ocflfs.ErrOpUnsupported.
+func (fsys *FS) WalkFiles(ctx context.Context, dir string) iter.Seq2[*ocflfs.FileRef, error] {
+	return func(yield func(*ocflfs.FileRef, error) bool) {
+		idx, rootDir, err := fsys.indexes.Find(ctx, fsys, dir)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		if idx == nil {
+			err := fmt.Errorf("listing files over http requires a storage root index (see 'ocfl index build'): %w", ocflfs.ErrOpUnsupported)
+			yield(nil, &fs.PathError{Op: "walk", Path: dir, Err: err})
+			return
+		}
+		for ref, err := range idx.WalkFiles(fsys, rootDir, relDir(rootDir, dir)) {
+			if !yield(ref, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// DirEntries implements ocflfs.DirEntriesFS for directories in storage roots
+// with an index created by 'ocfl index build'. Storage root directories
+// without an index can be opened without listing their contents: entries for
+// the storage root declaration, layout configuration, and the extensions
+// directory are found using HEAD requests. For other directories, the iterator
+// yields an error wrapping ocflfs.ErrOpUnsupported.
+func (fsys *FS) DirEntries(ctx context.Context, name string) iter.Seq2[fs.DirEntry, error] {
+	return func(yield func(fs.DirEntry, error) bool) {
+		idx, rootDir, err := fsys.indexes.Find(ctx, fsys, name)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		if idx != nil {
+			entries, err := idx.DirEntries(relDir(rootDir, name))
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, e := range entries {
+				if !yield(e, nil) {
+					return
+				}
+			}
+			return
+		}
+		var entries []fs.DirEntry
+		for _, decl := range rootDeclarations {
+			entry, err := fsys.fileEntry(ctx, path.Join(name, decl))
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+				yield(nil, err)
+				return
+			}
+			entries = append(entries, entry)
+			break
+		}
+		if len(entries) == 0 {
+			yield(nil, &fs.PathError{Op: "readdir", Path: name, Err: ocflfs.ErrOpUnsupported})
+			return
+		}
+		for _, config := range rootConfigFiles {
+			entry, err := fsys.fileEntry(ctx, path.Join(name, config))
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+				yield(nil, err)
+				return
+			}
+			entries = append(entries, entry)
+		}
+		// The extensions directory can't be found with a HEAD request. Including
+		// it means walking the storage root fails with ErrOpUnsupported rather
+		// than finding no objects.
+		entries = append(entries, dirEntry("extensions"))
+		for _, e := range entries {
+			if !yield(e, nil) {
+				return
+			}
+		}
+	}
+}
+
+// relDir returns dir relative to rootDir, which must be dir or one of its
+// parents.
+func relDir(rootDir, dir string) string {
+	dir = path.Clean(dir)
+	switch {
+	case dir == rootDir:
+		return "."
+	case rootDir == ".":
+		return dir
+	}
+	return strings.TrimPrefix(dir, rootDir+"/")
+}
+
+func (fsys *FS) fileEntry(ctx context.Context, name string) (fs.DirEntry, error) {
+	f, err := fsys.OpenFile(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return fs.FileInfoToDirEntry(info), nil
+}
+
+// dirEntry is a directory that is assumed to exist.
+type dirEntry string
+
+func (d dirEntry) Name() string               { return string(d) }
+func (d dirEntry) IsDir() bool                { return true }
+func (d dirEntry) Type() fs.FileMode          { return fs.ModeDir }
+func (d dirEntry) Info() (fs.FileInfo, error) { return d, nil }
+func (d dirEntry) Size() int64                { return 0 }
+func (d dirEntry) Mode() fs.FileMode          { return fs.ModeDir | 0555 }
+func (d dirEntry) ModTime() time.Time         { return time.Time{} }
+func (d dirEntry) Sys() any                   { return nil }
+
+// namedFile is a file with the name of the unescaped path used to open it.
+type namedFile struct {
+	fs.File
+	name string
+}
+
+func (f *namedFile) Stat() (fs.FileInfo, error) {
+	info, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return namedInfo{FileInfo: info, name: f.name}, nil
+}
+
+type namedInfo struct {
+	fs.FileInfo
+	name string
+}
+
+func (i namedInfo) Name() string { return i.name }
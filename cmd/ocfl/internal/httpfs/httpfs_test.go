@@ -0,0 +1,53 @@
+package httpfs_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/httpfs"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/testutil"
+)
+
+func TestFS(t *testing.T) {
+	ctx := context.Background()
+	t.Run("escaped names", func(t *testing.T) {
+		dir := t.TempDir()
+		objDir := filepath.Join(dir, "ark%3a123%2fabc")
+		be.NilErr(t, os.Mkdir(objDir, 0755))
+		be.NilErr(t, os.WriteFile(filepath.Join(objDir, "a%20b.txt"), []byte("content"), 0644))
+		srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+		defer srv.Close()
+		fsys := httpfs.New(srv.URL)
+		data, err := ocflfs.ReadAll(ctx, fsys, "ark%3a123%2fabc/a%20b.txt")
+		be.NilErr(t, err)
+		be.Equal(t, "content", string(data))
+		info, err := ocflfs.StatFile(ctx, fsys, "ark%3a123%2fabc/a%20b.txt")
+		be.NilErr(t, err)
+		be.Equal(t, "a%20b.txt", info.Name())
+		_, err = ocflfs.StatFile(ctx, fsys, "ark%3a123%2fabc/missing%20file.txt")
+		be.True(t, errors.Is(err, fs.ErrNotExist))
+	})
+	t.Run("storage root entries", func(t *testing.T) {
+		srv := httptest.NewServer(http.FileServer(http.FS(testutil.TestDataFS())))
+		defer srv.Close()
+		fsys := httpfs.New(srv.URL)
+		entries, err := ocflfs.ReadDir(ctx, fsys, "testdata/store-fixtures/1.0/good-stores/reg-extension-dir-root")
+		be.NilErr(t, err)
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		be.AllEqual(t, []string{"0=ocfl_1.0", "extensions", "ocfl_layout.json"}, names)
+		// other directories can't be listed
+		_, err = ocflfs.ReadDir(ctx, fsys, "testdata/store-fixtures/1.0/good-stores/reg-extension-dir-root/a47")
+		be.True(t, errors.Is(err, ocflfs.ErrOpUnsupported))
+	})
+}
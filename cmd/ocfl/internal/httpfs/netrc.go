@@ -0,0 +1,107 @@
+package httpfs
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// netrcLogin is a login from a .netrc file.
+type netrcLogin struct {
+	login    string
+	password string
+}
+
+// readNetrc returns the login for host from the .netrc file at name. If the
+// file doesn't exist or doesn't have a login for the host (or a default
+// login), an empty login is returned.
+func readNetrc(name string, host string) (netrcLogin, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return netrcLogin{}, nil
+		}
+		return netrcLogin{}, fmt.Errorf("reading netrc file: %w", err)
+	}
+	defer f.Close()
+	var (
+		tokens   []string
+		inMacro  bool // macro definitions continue until an empty line
+		scanner  = bufio.NewScanner(f)
+		defLogin *netrcLogin
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if inMacro {
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		for i, field := range fields {
+			if field == "macdef" {
+				fields = fields[:i]
+				inMacro = true
+				break
+			}
+		}
+		tokens = append(tokens, fields...)
+	}
+	if err := scanner.Err(); err != nil {
+		return netrcLogin{}, fmt.Errorf("reading netrc file: %w", err)
+	}
+	var (
+		current *netrcLogin
+		isHost  bool
+		found   *netrcLogin
+	)
+	for i := 0; i < len(tokens); i++ {
+		next := func() string {
+			if i+1 < len(tokens) {
+				i++
+				return tokens[i]
+			}
+			return ""
+		}
+		switch tokens[i] {
+		case "machine":
+			if isHost && found == nil {
+				found = current
+			}
+			current = &netrcLogin{}
+			isHost = next() == host
+		case "default":
+			if isHost && found == nil {
+				found = current
+			}
+			current = &netrcLogin{}
+			isHost = false
+			defLogin = current
+		case "login":
+			if current != nil {
+				current.login = next()
+			}
+		case "password":
+			if current != nil {
+				current.password = next()
+			}
+		case "account":
+			next()
+		}
+	}
+	if isHost && found == nil {
+		found = current
+	}
+	switch {
+	case found != nil:
+		return *found, nil
+	case defLogin != nil:
+		return *defLogin, nil
+	}
+	return netrcLogin{}, nil
+}
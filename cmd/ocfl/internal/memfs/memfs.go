@@ -0,0 +1,253 @@
+// Package memfs provides an in-memory, read-write ocflfs.FS.
+package memfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"iter"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+)
+
+var (
+	namedMu sync.Mutex
+	named   = map[string]*FS{}
+)
+
+// FS is an in-memory file system. It is safe for concurrent use.
+type FS struct {
+	name  string
+	mu    sync.RWMutex
+	files map[string]*file
+}
+
+var (
+	_ ocflfs.CopyFS       = (*FS)(nil)
+	_ ocflfs.DirEntriesFS = (*FS)(nil)
+)
+
+type file struct {
+	data    []byte
+	modTime time.Time
+}
+
+// New returns a new, empty FS.
+func New() *FS {
+	return &FS{files: map[string]*file{}}
+}
+
+// Named returns the FS with the given name, creating it if necessary. FSs with
+// the same name are shared for the life of the process.
+func Named(name string) *FS {
+	namedMu.Lock()
+	defer namedMu.Unlock()
+	fsys := named[name]
+	if fsys == nil {
+		fsys = New()
+		fsys.name = name
+		named[name] = fsys
+	}
+	return fsys
+}
+
+// Name returns the FS's name, if it was created with Named.
+func (fsys *FS) Name() string { return fsys.name }
+
+// OpenFile opens the named file for reading.
+func (fsys *FS) OpenFile(ctx context.Context, name string) (fs.File, error) {
+	if err := checkPath(ctx, "openfile", name); err != nil {
+		return nil, err
+	}
+	fsys.mu.RLock()
+	defer fsys.mu.RUnlock()
+	f := fsys.files[name]
+	if f == nil {
+		err := fs.ErrNotExist
+		if fsys.isDir(name) {
+			err = ocflfs.ErrNotFile
+		}
+		return nil, &fs.PathError{Op: "openfile", Path: name, Err: err}
+	}
+	return &openFile{
+		Reader: bytes.NewReader(f.data),
+		info:   fileInfo{name: path.Base(name), size: int64(len(f.data)), modTime: f.modTime},
+	}, nil
+}
+
+// DirEntries implements ocflfs.DirEntriesFS.
+func (fsys *FS) DirEntries(ctx context.Context, name string) iter.Seq2[fs.DirEntry, error] {
+	return func(yield func(fs.DirEntry, error) bool) {
+		if err := checkPath(ctx, "readdir", name); err != nil {
+			yield(nil, err)
+			return
+		}
+		fsys.mu.RLock()
+		entries := map[string]fs.DirEntry{}
+		for filePath, f := range fsys.files {
+			rel, ok := relPath(name, filePath)
+			if !ok {
+				continue
+			}
+			entryName, _, isDir := strings.Cut(rel, "/")
+			if _, exists := entries[entryName]; exists {
+				continue
+			}
+			info := fileInfo{name: entryName, size: int64(len(f.data)), modTime: f.modTime, dir: isDir}
+			entries[entryName] = fs.FileInfoToDirEntry(info)
+		}
+		_, isFile := fsys.files[name]
+		fsys.mu.RUnlock()
+		if len(entries) == 0 && name != "." {
+			err := fs.ErrNotExist
+			if isFile {
+				err = errors.New("not a directory")
+			}
+			yield(nil, &fs.PathError{Op: "readdir", Path: name, Err: err})
+			return
+		}
+		for _, entryName := range slices.Sorted(maps.Keys(entries)) {
+			if !yield(entries[entryName], nil) {
+				return
+			}
+		}
+	}
+}
+
+// Write creates or replaces the named file with the contents of r.
+func (fsys *FS) Write(ctx context.Context, name string, r io.Reader) (int64, error) {
+	if err := checkPath(ctx, "write", name); err != nil {
+		return 0, err
+	}
+	if name == "." {
+		return 0, &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return 0, &fs.PathError{Op: "write", Path: name, Err: err}
+	}
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if fsys.isDir(name) {
+		return 0, &fs.PathError{Op: "write", Path: name, Err: ocflfs.ErrNotFile}
+	}
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if _, isFile := fsys.files[dir]; isFile {
+			return 0, &fs.PathError{Op: "write", Path: name, Err: errors.New("parent is a file")}
+		}
+	}
+	fsys.files[name] = &file{data: data, modTime: time.Now()}
+	return int64(len(data)), nil
+}
+
+// Remove removes the named file.
+func (fsys *FS) Remove(ctx context.Context, name string) error {
+	if err := checkPath(ctx, "remove", name); err != nil {
+		return err
+	}
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if _, exists := fsys.files[name]; !exists {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(fsys.files, name)
+	return nil
+}
+
+// RemoveAll removes the named file or directory and its contents.
+func (fsys *FS) RemoveAll(ctx context.Context, name string) error {
+	if err := checkPath(ctx, "remove", name); err != nil {
+		return err
+	}
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	for filePath := range fsys.files {
+		if _, ok := relPath(name, filePath); ok || filePath == name {
+			delete(fsys.files, filePath)
+		}
+	}
+	return nil
+}
+
+// Copy copies the file src to dst.
+func (fsys *FS) Copy(ctx context.Context, dst string, src string) (int64, error) {
+	if err := checkPath(ctx, "copy", src); err != nil {
+		return 0, err
+	}
+	fsys.mu.RLock()
+	f := fsys.files[src]
+	fsys.mu.RUnlock()
+	if f == nil {
+		return 0, &fs.PathError{Op: "copy", Path: src, Err: fs.ErrNotExist}
+	}
+	// file data is never modified, so it can be shared.
+	return fsys.Write(ctx, dst, bytes.NewReader(f.data))
+}
+
+// isDir returns true if name is a directory. The caller must hold fsys.mu.
+func (fsys *FS) isDir(name string) bool {
+	for filePath := range fsys.files {
+		if _, ok := relPath(name, filePath); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func checkPath(ctx context.Context, op string, name string) error {
+	if err := ctx.Err(); err != nil {
+		return &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return nil
+}
+
+// relPath returns name relative to dir, if name is in dir.
+func relPath(dir string, name string) (string, bool) {
+	if dir == "." {
+		return name, true
+	}
+	return strings.CutPrefix(name, dir+"/")
+}
+
+type openFile struct {
+	*bytes.Reader
+	info fileInfo
+}
+
+func (f *openFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *openFile) Close() error               { return nil }
+
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (i fileInfo) Name() string { return i.name }
+func (i fileInfo) Size() int64 {
+	if i.dir {
+		return 0
+	}
+	return i.size
+}
+func (i fileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0o755
+	}
+	return 0o644
+}
+func (i fileInfo) ModTime() time.Time { return i.modTime }
+func (i fileInfo) IsDir() bool        { return i.dir }
+func (i fileInfo) Sys() any           { return nil }
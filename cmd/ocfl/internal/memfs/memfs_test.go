@@ -0,0 +1,51 @@
+package memfs_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/memfs"
+)
+
+func TestFS(t *testing.T) {
+	ctx := context.Background()
+	fsys := memfs.New()
+	for _, name := range []string{"a/b/c.txt", "a/d.txt", "e.txt"} {
+		_, err := ocflfs.Write(ctx, fsys, name, strings.NewReader(name))
+		be.NilErr(t, err)
+	}
+	got, err := ocflfs.ReadAll(ctx, fsys, "a/b/c.txt")
+	be.NilErr(t, err)
+	be.Equal(t, "a/b/c.txt", string(got))
+	entries, err := ocflfs.ReadDir(ctx, fsys, "a")
+	be.NilErr(t, err)
+	be.Equal(t, 2, len(entries))
+	be.Equal(t, "b", entries[0].Name())
+	be.True(t, entries[0].IsDir())
+	be.Equal(t, "d.txt", entries[1].Name())
+	// errors
+	_, err = fsys.OpenFile(ctx, "a")
+	be.True(t, errors.Is(err, ocflfs.ErrNotFile))
+	_, err = fsys.OpenFile(ctx, "missing")
+	be.True(t, errors.Is(err, fs.ErrNotExist))
+	_, err = ocflfs.ReadDir(ctx, fsys, "missing")
+	be.True(t, errors.Is(err, fs.ErrNotExist))
+	_, err = ocflfs.Write(ctx, fsys, "e.txt/f.txt", strings.NewReader(""))
+	be.True(t, err != nil)
+	// copy and remove
+	_, err = ocflfs.Copy(ctx, fsys, "f.txt", fsys, "e.txt")
+	be.NilErr(t, err)
+	be.NilErr(t, ocflfs.Remove(ctx, fsys, "e.txt"))
+	be.NilErr(t, ocflfs.RemoveAll(ctx, fsys, "a"))
+	entries, err = ocflfs.ReadDir(ctx, fsys, ".")
+	be.NilErr(t, err)
+	be.Equal(t, 1, len(entries))
+	be.Equal(t, "f.txt", entries[0].Name())
+	// named file systems are shared
+	be.Equal(t, memfs.Named("test"), memfs.Named("test"))
+}
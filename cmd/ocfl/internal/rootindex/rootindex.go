@@ -0,0 +1,315 @@
+// Package rootindex provides a static index of the objects in a storage root.
+// The index is used to enumerate objects in storage roots that can't list
+// directories, like those accessed over HTTP.
+package rootindex
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"iter"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/srerickson/ocfl-go"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// Extension is the name of the storage root extension directory with the
+	// index.
+	Extension = "ocfl-tools-index"
+	// FileName is the name of the index file in the extension directory.
+	FileName = "index.json"
+
+	extensionsDir = "extensions"
+)
+
+// Path is the index file's path relative to the storage root.
+var Path = path.Join(extensionsDir, Extension, FileName)
+
+// Index is a static index of the objects in a storage root.
+type Index struct {
+	Created time.Time `json:"created"`
+	// Objects are sorted by path.
+	Objects []Object `json:"objects"`
+	// Files are all the files in the storage root, sorted by path. The
+	// index itself isn't included.
+	Files []File `json:"files"`
+}
+
+// Object is an object in the storage root.
+type Object struct {
+	ID              string `json:"id"`
+	Path            string `json:"path"` // relative to the storage root
+	Head            string `json:"head"`
+	InventoryDigest string `json:"inventory_digest"`
+}
+
+// File is a file in the storage root.
+type File struct {
+	Path string `json:"path"` // relative to the storage root
+	Size int64  `json:"size"`
+}
+
+// Build returns a new index for the storage root. Objects are read in numgos
+// goroutines.
+func Build(ctx context.Context, root *ocfl.Root, numgos int) (*Index, error) {
+	idx := &Index{Created: time.Now().UTC().Truncate(time.Second)}
+	fsys, rootDir := root.FS(), root.Path()
+	indexDir := path.Join(extensionsDir, Extension) + "/"
+	var objDirs []string
+	for file, err := range ocflfs.WalkFiles(ctx, fsys, rootDir) {
+		if err != nil {
+			return nil, fmt.Errorf("reading storage root: %w", err)
+		}
+		if strings.HasPrefix(file.Path, indexDir) {
+			continue
+		}
+		idx.Files = append(idx.Files, File{Path: file.Path, Size: file.Info.Size()})
+		decl, err := ocfl.ParseNamaste(path.Base(file.Path))
+		if err == nil && decl.IsObject() && strings.Contains(file.Path, "/") {
+			objDirs = append(objDirs, path.Dir(file.Path))
+		}
+	}
+	idx.Objects = make([]Object, len(objDirs))
+	grp, grpCtx := errgroup.WithContext(ctx)
+	grp.SetLimit(max(numgos, 1))
+	for i, objDir := range objDirs {
+		grp.Go(func() error {
+			obj, err := ocfl.NewObject(grpCtx, fsys, path.Join(rootDir, objDir), ocfl.ObjectMustExist())
+			if err != nil {
+				return fmt.Errorf("reading object at %q: %w", objDir, err)
+			}
+			idx.Objects[i] = Object{
+				ID:              obj.ID(),
+				Path:            objDir,
+				Head:            obj.Head().String(),
+				InventoryDigest: obj.InventoryDigest(),
+			}
+			return nil
+		})
+	}
+	if err := grp.Wait(); err != nil {
+		return nil, err
+	}
+	idx.sort()
+	return idx, nil
+}
+
+// Read reads the index for the storage root at rootDir in fsys.
+func Read(ctx context.Context, fsys ocflfs.FS, rootDir string) (*Index, error) {
+	b, err := ocflfs.ReadAll(ctx, fsys, path.Join(rootDir, Path))
+	if err != nil {
+		return nil, err
+	}
+	var idx Index
+	if err := json.Unmarshal(b, &idx); err != nil {
+		return nil, fmt.Errorf("decoding storage root index: %w", err)
+	}
+	idx.sort()
+	return &idx, nil
+}
+
+// Write writes the index to the storage root at rootDir in fsys.
+func (idx *Index) Write(ctx context.Context, fsys ocflfs.FS, rootDir string) error {
+	b, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = ocflfs.Write(ctx, fsys, path.Join(rootDir, Path), bytes.NewReader(b))
+	return err
+}
+
+// Compare returns descriptions of differences between idx and current: the
+// changes that make idx stale. Changes to files in object roots are reported
+// as changes to the objects.
+func (idx *Index) Compare(current *Index) []string {
+	var changes []string
+	indexed := make(map[string]Object, len(idx.Objects))
+	for _, obj := range idx.Objects {
+		indexed[obj.Path] = obj
+	}
+	for _, obj := range current.Objects {
+		prev, ok := indexed[obj.Path]
+		delete(indexed, obj.Path)
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("object not in index: %s", obj.ID))
+		case prev.Head != obj.Head:
+			changes = append(changes, fmt.Sprintf("object updated: %s (%s -> %s)", obj.ID, prev.Head, obj.Head))
+		case prev.InventoryDigest != obj.InventoryDigest || prev.ID != obj.ID:
+			changes = append(changes, fmt.Sprintf("object changed: %s", obj.ID))
+		}
+	}
+	for _, obj := range idx.Objects {
+		if _, ok := indexed[obj.Path]; ok {
+			changes = append(changes, fmt.Sprintf("object not found: %s", obj.ID))
+		}
+	}
+	objDirs := map[string]bool{}
+	for _, obj := range slices.Concat(idx.Objects, current.Objects) {
+		objDirs[obj.Path] = true
+	}
+	inObject := func(name string) bool {
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if objDirs[dir] {
+				return true
+			}
+		}
+		return false
+	}
+	indexedFiles := make(map[string]File, len(idx.Files))
+	for _, f := range idx.Files {
+		indexedFiles[f.Path] = f
+	}
+	for _, f := range current.Files {
+		prev, ok := indexedFiles[f.Path]
+		delete(indexedFiles, f.Path)
+		switch {
+		case inObject(f.Path):
+		case !ok:
+			changes = append(changes, fmt.Sprintf("file not in index: %s", f.Path))
+		case prev.Size != f.Size:
+			changes = append(changes, fmt.Sprintf("file changed: %s", f.Path))
+		}
+	}
+	for _, f := range idx.Files {
+		if _, ok := indexedFiles[f.Path]; ok && !inObject(f.Path) {
+			changes = append(changes, fmt.Sprintf("file not found: %s", f.Path))
+		}
+	}
+	return changes
+}
+
+// WalkFiles returns an iterator that yields the indexed files in dir, relative
+// to the storage root.
+func (idx *Index) WalkFiles(fsys ocflfs.FS, rootDir string, dir string) iter.Seq2[*ocflfs.FileRef, error] {
+	return func(yield func(*ocflfs.FileRef, error) bool) {
+		for _, f := range idx.Files {
+			rel, ok := relPath(dir, f.Path)
+			if !ok {
+				continue
+			}
+			ref := &ocflfs.FileRef{
+				FS:      fsys,
+				BaseDir: path.Join(rootDir, dir),
+				Path:    rel,
+				Info:    fileInfo{name: path.Base(f.Path), size: f.Size},
+			}
+			if !yield(ref, nil) {
+				return
+			}
+		}
+	}
+}
+
+// DirEntries returns entries for the directory dir, relative to the storage
+// root. It returns an error wrapping fs.ErrNotExist if dir isn't in the index.
+func (idx *Index) DirEntries(dir string) ([]fs.DirEntry, error) {
+	var entries []fs.DirEntry
+	for _, f := range idx.Files {
+		rel, ok := relPath(dir, f.Path)
+		if !ok {
+			continue
+		}
+		name, _, isDir := strings.Cut(rel, "/")
+		// files are sorted, so entries for the same directory are adjacent.
+		if n := len(entries); n > 0 && entries[n-1].Name() == name {
+			continue
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(fileInfo{name: name, size: f.Size, dir: isDir}))
+	}
+	if len(entries) == 0 && dir != "." {
+		return nil, &fs.PathError{Op: "readdir", Path: dir, Err: fs.ErrNotExist}
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int { return cmp.Compare(a.Name(), b.Name()) })
+	return entries, nil
+}
+
+func (idx *Index) sort() {
+	slices.SortFunc(idx.Files, func(a, b File) int { return cmp.Compare(a.Path, b.Path) })
+	slices.SortFunc(idx.Objects, func(a, b Object) int { return cmp.Compare(a.Path, b.Path) })
+}
+
+// relPath returns name relative to dir, if name is in dir.
+func relPath(dir string, name string) (string, bool) {
+	if dir == "." {
+		return name, true
+	}
+	return strings.CutPrefix(name, dir+"/")
+}
+
+// Cache caches indexes read from an FS.
+type Cache struct {
+	indexes sync.Map // root dir -> *Index (or nil if there is no index)
+}
+
+// Find returns the index for the storage root containing dir: an index in
+// dir or one of its parents. It returns nil if no index is found.
+func (c *Cache) Find(ctx context.Context, fsys ocflfs.FS, dir string) (*Index, string, error) {
+	dir = path.Clean(dir)
+	// check indexes that have already been found before reading parents.
+	var (
+		found    *Index
+		foundDir string
+	)
+	c.indexes.Range(func(key, val any) bool {
+		idx, rootDir := val.(*Index), key.(string)
+		if idx != nil && (rootDir == "." || dir == rootDir || strings.HasPrefix(dir, rootDir+"/")) {
+			found, foundDir = idx, rootDir
+			return false
+		}
+		return true
+	})
+	if found != nil {
+		return found, foundDir, nil
+	}
+	for rootDir := dir; ; rootDir = path.Dir(rootDir) {
+		val, ok := c.indexes.Load(rootDir)
+		if !ok {
+			idx, err := Read(ctx, fsys, rootDir)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, "", err
+			}
+			val, _ = c.indexes.LoadOrStore(rootDir, idx)
+		}
+		if idx := val.(*Index); idx != nil {
+			return idx, rootDir, nil
+		}
+		if rootDir == "." {
+			return nil, "", nil
+		}
+	}
+}
+
+type fileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i fileInfo) Name() string { return i.name }
+func (i fileInfo) Size() int64 {
+	if i.dir {
+		return 0
+	}
+	return i.size
+}
+func (i fileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+func (i fileInfo) ModTime() time.Time { return time.Time{} }
+func (i fileInfo) IsDir() bool        { return i.dir }
+func (i fileInfo) Sys() any           { return nil }
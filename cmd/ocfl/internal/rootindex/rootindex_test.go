@@ -0,0 +1,77 @@
+package rootindex_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-go"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-go/fs/local"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/rootindex"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/testutil"
+)
+
+func TestIndex(t *testing.T) {
+	ctx := context.Background()
+	_, fixtures := testutil.TempDirTestData(t,
+		"testdata/store-fixtures/1.0/good-stores/reg-extension-dir-root",
+	)
+	fsys, err := local.NewFS(fixtures[0])
+	be.NilErr(t, err)
+	root, err := ocfl.NewRoot(ctx, fsys, ".")
+	be.NilErr(t, err)
+	idx, err := rootindex.Build(ctx, root, 2)
+	be.NilErr(t, err)
+	be.Equal(t, 1, len(idx.Objects))
+	be.Equal(t, "ark:123/abc", idx.Objects[0].ID)
+	be.Equal(t, "v1", idx.Objects[0].Head)
+	be.Nonzero(t, idx.Objects[0].InventoryDigest)
+	be.NilErr(t, idx.Write(ctx, fsys, "."))
+
+	t.Run("read", func(t *testing.T) {
+		read, err := rootindex.Read(ctx, fsys, ".")
+		be.NilErr(t, err)
+		be.DeepEqual(t, idx, read)
+		// the written index isn't included in a new index
+		rebuilt, err := rootindex.Build(ctx, root, 2)
+		be.NilErr(t, err)
+		be.Equal(t, 0, len(idx.Compare(rebuilt)))
+	})
+	t.Run("dir entries", func(t *testing.T) {
+		entries, err := idx.DirEntries(".")
+		be.NilErr(t, err)
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		be.AllEqual(t, []string{"0=ocfl_1.0", "a47", "extensions", "ocfl_layout.json"}, names)
+		be.True(t, entries[1].IsDir())
+		objEntries, err := idx.DirEntries(idx.Objects[0].Path)
+		be.NilErr(t, err)
+		be.Nonzero(t, len(objEntries))
+		_, err = idx.DirEntries("missing")
+		be.True(t, err != nil)
+	})
+	t.Run("walk files", func(t *testing.T) {
+		var count int
+		for ref, err := range idx.WalkFiles(fsys, ".", idx.Objects[0].Path) {
+			be.NilErr(t, err)
+			_, err := ocflfs.StatFile(ctx, ref.FS, ref.FullPath())
+			be.NilErr(t, err)
+			count++
+		}
+		be.Nonzero(t, count)
+	})
+	t.Run("compare", func(t *testing.T) {
+		current := *idx
+		current.Objects = []rootindex.Object{idx.Objects[0], {ID: "new", Path: "new"}}
+		current.Objects[0].Head = "v2"
+		current.Files = append(current.Files[1:], rootindex.File{Path: "new/0=ocfl_object_1.0"})
+		be.AllEqual(t, []string{
+			"object updated: ark:123/abc (v1 -> v2)",
+			"object not in index: new",
+			"file not found: " + idx.Files[0].Path,
+		}, idx.Compare(&current))
+	})
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+var (
+	errUnauthorized = errors.New("missing or invalid access token")
+	errForbidden    = errors.New("access token is not authorized for the object")
+)
+
+// Tokens maps access tokens to the object IDs they are authorized to update.
+// IDs ending in '*' match any ID with the prefix.
+type Tokens map[string][]string
+
+// ReadTokens reads access tokens from a file. Each line has a token followed
+// by one or more object IDs, separated by whitespace. Blank lines and lines
+// starting with '#' are ignored. For example:
+//
+//	# token      object IDs
+//	s3cr3t       *
+//	ingest-tok   ark:123/* ark:456/xyz
+func ReadTokens(name string) (Tokens, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	tokens := Tokens{}
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s, line %d: expected a token and at least one object ID", name, lineNum)
+		}
+		tokens[fields[0]] = append(tokens[fields[0]], fields[1:]...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// authorize returns an error if the request's bearer token is not authorized
+// to update the object with the id.
+func (t Tokens) authorize(r *http.Request, id string) error {
+	reqToken, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || reqToken == "" {
+		return errUnauthorized
+	}
+	var ids []string
+	var known bool
+	for token, tokenIDs := range t {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(reqToken)) == 1 {
+			ids, known = tokenIDs, true
+		}
+	}
+	if !known {
+		return errUnauthorized
+	}
+	for _, pattern := range ids {
+		if prefix, isPrefix := strings.CutSuffix(pattern, "*"); isPrefix && strings.HasPrefix(id, prefix) {
+			return nil
+		}
+		if pattern == id {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", errForbidden, id)
+}
@@ -0,0 +1,491 @@
+// Package server provides an HTTP interface for browsing and downloading
+// objects in an OCFL storage root. Optionally, it also provides endpoints for
+// staging and committing object updates; requests to these require a bearer
+// token that is authorized for the object.
+package server
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/srerickson/ocfl-go"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"objectURL":  objectURL,
+	"versionURL": versionURL,
+	"time":       func(t time.Time) string { return t.Format(time.RFC3339) },
+}).ParseFS(templateFS, "templates/*.html"))
+
+// Server is an http.Handler for browsing objects in a storage root and
+// downloading their contents. Pages are HTML by default; JSON is returned if
+// the request includes the query parameter format=json or accepts
+// application/json.
+//
+// Routes:
+//
+//	GET /objects/                                   list objects
+//	GET /objects/{id}                               object summary and version log
+//	GET /objects/{id}/inventory.json                object inventory
+//	GET /objects/{id}/versions/{version}/{path...}  directory listing or file download
+//
+// IDs are path-escaped. Versions are "head" or version names like "v1". See
+// [WithWrites] for endpoints used to update objects:
+//
+//	POST   /stages/                         create a stage for an object
+//	GET    /stages/{stage}                  stage state and errors
+//	DELETE /stages/{stage}                  discard the stage
+//	PUT    /stages/{stage}/files/{path...}  upload a file (request body)
+//	POST   /stages/{stage}/files/{path...}  upload files to a directory (multipart/form-data)
+//	DELETE /stages/{stage}/files/{path...}  remove a file (or a directory, with recursive=true)
+//	GET    /stages/{stage}/diff             changes from the object's head version
+//	POST   /stages/{stage}/commit           commit the stage as a new object version
+type Server struct {
+	root   *ocfl.Root
+	logger *slog.Logger
+	mux    *http.ServeMux
+	writes *writeConfig // nil if write endpoints are disabled
+
+	maxUploadSize int64 // maximum request body size for uploads, if > 0
+}
+
+// Option is used to configure a [Server]
+type Option func(*Server)
+
+// WithLogger sets the logger used for errors.
+func WithLogger(l *slog.Logger) Option {
+	return func(s *Server) {
+		s.logger = l
+	}
+}
+
+// New returns a new Server for the storage root.
+func New(root *ocfl.Root, opts ...Option) *Server {
+	s := &Server{
+		root:          root,
+		logger:        slog.New(slog.DiscardHandler),
+		mux:           http.NewServeMux(),
+		maxUploadSize: DefaultMaxUploadSize,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	s.mux.Handle("GET /{$}", http.RedirectHandler("/objects/", http.StatusFound))
+	s.mux.HandleFunc("GET /objects/{$}", s.listObjects)
+	s.mux.HandleFunc("GET /objects/{id}", s.showObject)
+	s.mux.HandleFunc("GET /objects/{id}/inventory.json", s.showInventory)
+	s.mux.HandleFunc("GET /objects/{id}/versions/{version}/{path...}", s.showVersionPath)
+	if s.writes != nil {
+		s.writeRoutes()
+	}
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// objectItem is an object in the object list
+type objectItem struct {
+	ID           string    `json:"id"`
+	Head         string    `json:"head"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// objectSummary is the object page
+type objectSummary struct {
+	ID              string        `json:"id"`
+	Spec            string        `json:"spec"`
+	DigestAlgorithm string        `json:"digest_algorithm"`
+	InventoryDigest string        `json:"inventory_digest"`
+	Head            string        `json:"head"`
+	Versions        []versionItem `json:"versions"`
+}
+
+// versionItem is an entry in an object's version log
+type versionItem struct {
+	Version string     `json:"version"`
+	Created time.Time  `json:"created"`
+	Message string     `json:"message"`
+	User    *ocfl.User `json:"user,omitempty"`
+}
+
+// treeListing is a directory in an object version
+type treeListing struct {
+	ID      string      `json:"id"`
+	Version string      `json:"version"`
+	Path    string      `json:"path"`
+	Entries []treeEntry `json:"entries"`
+}
+
+// treeEntry is a file or directory in a treeListing
+type treeEntry struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Dir    bool   `json:"dir,omitempty"`
+	Size   *int64 `json:"size,omitempty"`
+	Digest string `json:"digest,omitempty"`
+}
+
+func (s *Server) listObjects(w http.ResponseWriter, r *http.Request) {
+	items := []objectItem{}
+	for obj, err := range s.root.Objects(r.Context()) {
+		if err != nil {
+			s.error(w, r, fmt.Errorf("listing objects: %w", err))
+			return
+		}
+		items = append(items, objectItem{
+			ID:           obj.ID(),
+			Head:         obj.Head().String(),
+			LastModified: obj.Version(0).Created(),
+		})
+	}
+	s.render(w, r, "objects.html", items)
+}
+
+func (s *Server) showObject(w http.ResponseWriter, r *http.Request) {
+	obj, err := s.object(r)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	s.render(w, r, "object.html", newObjectSummary(obj))
+}
+
+func (s *Server) showInventory(w http.ResponseWriter, r *http.Request) {
+	obj, err := s.object(r)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	name := path.Join(obj.Path(), "inventory.json")
+	f, err := obj.FS().OpenFile(r.Context(), name)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		s.error(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(obj.InventoryDigest()))
+	content := &seekFile{
+		file: f,
+		size: info.Size(),
+		open: func() (fs.File, error) { return obj.FS().OpenFile(r.Context(), name) },
+	}
+	defer content.Close()
+	http.ServeContent(w, r, "inventory.json", obj.Version(0).Created(), content)
+}
+
+func (s *Server) showVersionPath(w http.ResponseWriter, r *http.Request) {
+	obj, err := s.object(r)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	vnum, err := parseVersion(r.PathValue("version"), obj.Head())
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	name := strings.TrimSuffix(r.PathValue("path"), "/")
+	if name == "" {
+		name = "."
+	}
+	if !fs.ValidPath(name) {
+		s.error(w, r, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid})
+		return
+	}
+	fsys, err := obj.VersionFS(r.Context(), vnum.Num())
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	f, err := fsys.Open(name)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		s.error(w, r, err)
+		return
+	}
+	state := obj.Version(vnum.Num()).State().PathMap()
+	if dir, ok := f.(fs.ReadDirFile); ok && info.IsDir() {
+		defer dir.Close()
+		entries, err := dir.ReadDir(-1)
+		if err != nil {
+			s.error(w, r, err)
+			return
+		}
+		listing := treeListing{
+			ID:      obj.ID(),
+			Version: r.PathValue("version"),
+			Path:    name,
+			Entries: make([]treeEntry, 0, len(entries)),
+		}
+		for _, e := range entries {
+			entry := treeEntry{Name: e.Name(), Path: path.Join(name, e.Name()), Dir: e.IsDir()}
+			if !entry.Dir {
+				entryInfo, err := e.Info()
+				if err != nil {
+					s.error(w, r, err)
+					return
+				}
+				size := entryInfo.Size()
+				entry.Size = &size
+				entry.Digest = state[entry.Path]
+			}
+			listing.Entries = append(listing.Entries, entry)
+		}
+		s.render(w, r, "tree.html", listing)
+		return
+	}
+	w.Header().Set("ETag", etag(state[name]))
+	content := &seekFile{
+		file: f,
+		size: info.Size(),
+		open: func() (fs.File, error) { return fsys.Open(name) },
+	}
+	defer content.Close()
+	http.ServeContent(w, r, path.Base(name), info.ModTime(), content)
+}
+
+func newObjectSummary(obj *ocfl.Object) objectSummary {
+	summary := objectSummary{
+		ID:              obj.ID(),
+		Spec:            string(obj.Spec()),
+		DigestAlgorithm: obj.DigestAlgorithm().ID(),
+		InventoryDigest: obj.InventoryDigest(),
+		Head:            obj.Head().String(),
+	}
+	for _, vnum := range obj.Head().Lineage() {
+		ver := obj.Version(vnum.Num())
+		summary.Versions = append(summary.Versions, versionItem{
+			Version: vnum.String(),
+			Created: ver.Created(),
+			Message: ver.Message(),
+			User:    ver.User(),
+		})
+	}
+	return summary
+}
+
+// object returns the existing object with the id in the request path.
+func (s *Server) object(r *http.Request) (*ocfl.Object, error) {
+	id := r.PathValue("id")
+	obj, err := s.root.NewObject(r.Context(), id, ocfl.ObjectMustExist())
+	if err != nil {
+		return nil, fmt.Errorf("reading object %q: %w", id, err)
+	}
+	return obj, nil
+}
+
+// render writes data as JSON or using the HTML template.
+func (s *Server) render(w http.ResponseWriter, r *http.Request, tmpl string, data any) {
+	if wantsJSON(r) {
+		if err := writeJSON(w, http.StatusOK, data); err != nil {
+			s.logger.Error("writing response", "path", r.URL.Path, "err", err)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := templates.ExecuteTemplate(w, tmpl, data); err != nil {
+		s.logger.Error("writing response", "path", r.URL.Path, "err", err)
+	}
+}
+
+// error writes an error response with a status code based on err, as JSON if
+// the request asks for it.
+func (s *Server) error(w http.ResponseWriter, r *http.Request, err error) {
+	s.writeError(w, r, err, wantsJSON(r))
+}
+
+// apiError writes an error response for write API requests, which is always
+// JSON.
+func (s *Server) apiError(w http.ResponseWriter, r *http.Request, err error) {
+	s.writeError(w, r, err, true)
+}
+
+func (s *Server) writeError(w http.ResponseWriter, r *http.Request, err error, asJSON bool) {
+	status := http.StatusInternalServerError
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, errUnauthorized):
+		status = http.StatusUnauthorized
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	case errors.Is(err, errForbidden):
+		status = http.StatusForbidden
+	case errors.Is(err, errConflict):
+		status = http.StatusConflict
+	case errors.Is(err, fs.ErrNotExist):
+		status = http.StatusNotFound
+	case errors.Is(err, fs.ErrInvalid), errors.Is(err, errInvalidVersion),
+		errors.Is(err, errBadRequest), errors.Is(err, errDigestMismatch):
+		status = http.StatusBadRequest
+	case errors.Is(err, ocflfs.ErrOpUnsupported):
+		status = http.StatusNotImplemented
+	}
+	if status == http.StatusInternalServerError {
+		s.logger.Error(err.Error(), "path", r.URL.Path)
+	}
+	if asJSON {
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	http.Error(w, err.Error(), status)
+}
+
+var errInvalidVersion = errors.New("invalid version")
+
+// parseVersion parses "head" or a version name like "v1". The version must
+// exist in an object with the head version.
+func parseVersion(val string, head ocfl.VNum) (ocfl.VNum, error) {
+	if val == "head" {
+		return head, nil
+	}
+	var vnum ocfl.VNum
+	if err := ocfl.ParseVNum(val, &vnum); err != nil {
+		return ocfl.VNum{}, fmt.Errorf("%w: %q", errInvalidVersion, val)
+	}
+	if vnum.Num() > head.Num() {
+		return ocfl.VNum{}, fmt.Errorf("version %s: %w", val, fs.ErrNotExist)
+	}
+	return vnum, nil
+}
+
+// wantsJSON returns true if the request asks for a JSON response.
+func wantsJSON(r *http.Request) bool {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return f == "json"
+	}
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// etag returns a strong entity tag for content with the digest.
+func etag(digest string) string {
+	return `"` + digest + `"`
+}
+
+func objectURL(id string) string {
+	return "/objects/" + url.PathEscape(id)
+}
+
+// versionURL returns the URL for the logical path name in the object version.
+func versionURL(id string, version string, name string) string {
+	u := objectURL(id) + "/versions/" + url.PathEscape(version) + "/"
+	if name == "." || name == "" {
+		return u
+	}
+	parts := strings.Split(name, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return u + strings.Join(parts, "/")
+}
+
+// seekFile implements io.ReadSeeker for files that may not be able to seek, so
+// they can be used with http.ServeContent. Files are opened on the first read.
+// If the file implements io.Seeker, it is used to change the read offset.
+// Otherwise, seeking forward discards content and seeking backward reopens the
+// file.
+type seekFile struct {
+	open    func() (fs.File, error)
+	file    fs.File
+	size    int64
+	pos     int64 // offset for the next read
+	filePos int64 // offset of file
+}
+
+func (f *seekFile) Read(p []byte) (int, error) {
+	if f.pos >= f.size {
+		return 0, io.EOF
+	}
+	if f.file == nil {
+		if err := f.reopen(); err != nil {
+			return 0, err
+		}
+	}
+	if f.filePos != f.pos {
+		if seeker, ok := f.file.(io.Seeker); ok {
+			if _, err := seeker.Seek(f.pos, io.SeekStart); err != nil {
+				return 0, err
+			}
+			f.filePos = f.pos
+		} else if f.filePos > f.pos {
+			if err := f.reopen(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if f.filePos < f.pos {
+		n, err := io.CopyN(io.Discard, f.file, f.pos-f.filePos)
+		f.filePos += n
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Read(p)
+	f.pos += int64(n)
+	f.filePos += int64(n)
+	return n, err
+}
+
+// reopen closes the file, if it's open, and opens it again at offset 0.
+func (f *seekFile) reopen() error {
+	if f.file != nil {
+		f.file.Close()
+	}
+	var err error
+	if f.file, err = f.open(); err != nil {
+		f.file = nil
+		return err
+	}
+	f.filePos = 0
+	return nil
+}
+
+func (f *seekFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.pos
+	case io.SeekEnd:
+		offset += f.size
+	}
+	if offset < 0 {
+		return 0, errors.New("seek to negative offset")
+	}
+	f.pos = offset
+	return offset, nil
+}
+
+func (f *seekFile) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
@@ -0,0 +1,186 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-go"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/server"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/testutil"
+)
+
+const (
+	rootFixture = "testdata/store-fixtures/1.0/good-stores/reg-extension-dir-root"
+	objectURL   = "/objects/ark:123%2Fabc"
+	fileURL     = objectURL + "/versions/head/a_file.txt"
+	fileContent = "Hello! I am a file.\n"
+)
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+	_, fixtures := testutil.TempDirTestData(t, rootFixture)
+	root, err := ocfl.NewRoot(ctx, ocflfs.DirFS(fixtures[0]), ".")
+	be.NilErr(t, err)
+	srv := httptest.NewServer(server.New(root))
+	defer srv.Close()
+
+	t.Run("list objects", func(t *testing.T) {
+		var objects []map[string]any
+		getJSON(t, srv.URL+"/objects/", &objects)
+		be.Equal(t, 1, len(objects))
+		be.Equal(t, "ark:123/abc", objects[0]["id"])
+		be.Equal(t, "v1", objects[0]["head"])
+	})
+	t.Run("list objects html", func(t *testing.T) {
+		resp, body := get(t, srv.URL+"/", nil)
+		be.Equal(t, http.StatusOK, resp.StatusCode)
+		be.In(t, "text/html", resp.Header.Get("Content-Type"))
+		be.In(t, `href="/objects/ark:123%2Fabc"`, body)
+	})
+	t.Run("object", func(t *testing.T) {
+		var obj struct {
+			ID       string `json:"id"`
+			Head     string `json:"head"`
+			Versions []struct {
+				Version string `json:"version"`
+				Message string `json:"message"`
+			} `json:"versions"`
+		}
+		getJSON(t, srv.URL+objectURL, &obj)
+		be.Equal(t, "ark:123/abc", obj.ID)
+		be.Equal(t, "v1", obj.Head)
+		be.Equal(t, 1, len(obj.Versions))
+		be.Equal(t, "v1", obj.Versions[0].Version)
+	})
+	t.Run("object not found", func(t *testing.T) {
+		resp, _ := get(t, srv.URL+"/objects/missing", nil)
+		be.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+	t.Run("inventory", func(t *testing.T) {
+		var inv map[string]any
+		getJSON(t, srv.URL+objectURL+"/inventory.json", &inv)
+		be.Equal(t, "ark:123/abc", inv["id"])
+	})
+	t.Run("tree", func(t *testing.T) {
+		var tree struct {
+			Path    string `json:"path"`
+			Entries []struct {
+				Name   string `json:"name"`
+				Size   int64  `json:"size"`
+				Digest string `json:"digest"`
+			} `json:"entries"`
+		}
+		getJSON(t, srv.URL+objectURL+"/versions/v1/", &tree)
+		be.Equal(t, ".", tree.Path)
+		be.Equal(t, 1, len(tree.Entries))
+		be.Equal(t, "a_file.txt", tree.Entries[0].Name)
+		be.Equal(t, int64(len(fileContent)), tree.Entries[0].Size)
+		be.Nonzero(t, tree.Entries[0].Digest)
+	})
+	t.Run("tree html", func(t *testing.T) {
+		resp, body := get(t, srv.URL+objectURL+"/versions/head/", nil)
+		be.Equal(t, http.StatusOK, resp.StatusCode)
+		be.In(t, `href="/objects/ark:123%2Fabc/versions/head/a_file.txt"`, body)
+		be.In(t, `20`, body)
+	})
+	t.Run("invalid version", func(t *testing.T) {
+		resp, _ := get(t, srv.URL+objectURL+"/versions/latest/", nil)
+		be.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp, _ = get(t, srv.URL+objectURL+"/versions/v2/", nil)
+		be.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+	t.Run("download", func(t *testing.T) {
+		resp, body := get(t, srv.URL+fileURL, nil)
+		be.Equal(t, http.StatusOK, resp.StatusCode)
+		be.Equal(t, fileContent, body)
+		be.Nonzero(t, resp.Header.Get("ETag"))
+		be.Equal(t, "bytes", resp.Header.Get("Accept-Ranges"))
+	})
+	t.Run("download missing file", func(t *testing.T) {
+		resp, _ := get(t, srv.URL+objectURL+"/versions/head/missing.txt", nil)
+		be.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+	t.Run("range", func(t *testing.T) {
+		resp, body := get(t, srv.URL+fileURL, http.Header{"Range": {"bytes=7-10"}})
+		be.Equal(t, http.StatusPartialContent, resp.StatusCode)
+		be.Equal(t, "I am", body)
+		resp, body = get(t, srv.URL+fileURL, http.Header{"Range": {"bytes=-5"}})
+		be.Equal(t, http.StatusPartialContent, resp.StatusCode)
+		be.Equal(t, "ile.\n", body)
+	})
+	t.Run("multiple ranges", func(t *testing.T) {
+		resp, body := get(t, srv.URL+fileURL, http.Header{"Range": {"bytes=7-10,14-17"}})
+		be.Equal(t, http.StatusPartialContent, resp.StatusCode)
+		be.In(t, "multipart/byteranges", resp.Header.Get("Content-Type"))
+		be.In(t, "I am", body)
+		be.In(t, "file", body)
+	})
+	t.Run("etag", func(t *testing.T) {
+		resp, _ := get(t, srv.URL+fileURL, nil)
+		etag := resp.Header.Get("ETag")
+		resp, body := get(t, srv.URL+fileURL, http.Header{"If-None-Match": {etag}})
+		be.Equal(t, http.StatusNotModified, resp.StatusCode)
+		be.Equal(t, "", body)
+	})
+}
+
+func get(t *testing.T, u string, header http.Header) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	be.NilErr(t, err)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	be.NilErr(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	be.NilErr(t, err)
+	return resp, string(body)
+}
+
+func getJSON(t *testing.T, u string, val any) {
+	t.Helper()
+	resp, body := get(t, u, http.Header{"Accept": {"application/json"}})
+	be.Equal(t, http.StatusOK, resp.StatusCode)
+	be.NilErr(t, json.NewDecoder(strings.NewReader(body)).Decode(val))
+}
+
+func TestServerNoSeek(t *testing.T) {
+	// range requests for files that can't seek
+	ctx := context.Background()
+	_, fixtures := testutil.TempDirTestData(t, rootFixture)
+	root, err := ocfl.NewRoot(ctx, noSeekFS{ocflfs.DirFS(fixtures[0])}, ".")
+	be.NilErr(t, err)
+	srv := httptest.NewServer(server.New(root))
+	defer srv.Close()
+	resp, body := get(t, srv.URL+fileURL, http.Header{"Range": {"bytes=7-10,0-4,14-17"}})
+	be.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	be.In(t, "I am", body)
+	be.In(t, "Hello", body)
+	be.In(t, "file", body)
+	resp, body = get(t, srv.URL+fileURL, http.Header{"Range": {"bytes=-5"}})
+	be.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	be.Equal(t, "ile.\n", body)
+}
+
+// noSeekFS returns files that don't implement io.Seeker.
+type noSeekFS struct{ ocflfs.DirEntriesFS }
+
+func (fsys noSeekFS) OpenFile(ctx context.Context, name string) (fs.File, error) {
+	f, err := fsys.DirEntriesFS.OpenFile(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return noSeekFile{f}, nil
+}
+
+type noSeekFile struct{ fs.File }
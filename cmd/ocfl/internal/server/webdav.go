@@ -0,0 +1,289 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/srerickson/ocfl-go"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"golang.org/x/net/webdav"
+)
+
+// davMethods are the WebDAV methods allowed by the read-only handler.
+const davMethods = "OPTIONS, GET, HEAD, PROPFIND"
+
+// NewWebDAV returns a read-only WebDAV handler for objects in the storage
+// root, with paths like:
+//
+//	/{id}/{version}/{path...}
+//
+// Versions are "head" or version names like "v1". In IDs, "/" and "%" are
+// escaped as "%2F" and "%25" so each ID is a single path segment. Requests
+// with methods other than OPTIONS, GET, HEAD, and PROPFIND are rejected. If
+// the storage root's objects can't be listed (as with storage roots
+// accessed over HTTP), the top-level collection is empty but objects can
+// still be accessed by ID.
+func NewWebDAV(root *ocfl.Root, logger *slog.Logger) http.Handler {
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	dav := &webdav.Handler{
+		FileSystem: &davFS{root: root, logger: logger},
+		LockSystem: webdav.NewMemLS(),
+		Logger: func(r *http.Request, err error) {
+			if err != nil && !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, fs.ErrPermission) {
+				logger.Error(err.Error(), "method", r.Method, "path", r.URL.Path)
+			}
+		},
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, "PROPFIND":
+			dav.ServeHTTP(w, r)
+		case http.MethodOptions:
+			w.Header().Set("Allow", davMethods)
+			w.Header().Set("DAV", "1")
+		default:
+			w.Header().Set("Allow", davMethods)
+			http.Error(w, "read-only", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// davFS implements webdav.FileSystem for objects in the storage root.
+type davFS struct {
+	root   *ocfl.Root
+	logger *slog.Logger
+}
+
+var _ webdav.FileSystem = (*davFS)(nil)
+
+func (d *davFS) Mkdir(_ context.Context, name string, _ os.FileMode) error {
+	return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrPermission}
+}
+
+func (d *davFS) RemoveAll(_ context.Context, name string) error {
+	return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrPermission}
+}
+
+func (d *davFS) Rename(_ context.Context, oldName, _ string) error {
+	return &fs.PathError{Op: "rename", Path: oldName, Err: fs.ErrPermission}
+}
+
+func (d *davFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	f, err := d.OpenFile(ctx, name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Stat()
+}
+
+func (d *davFS) OpenFile(ctx context.Context, name string, flag int, _ os.FileMode) (webdav.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	parts := strings.SplitN(strings.Trim(path.Clean("/"+name), "/"), "/", 3)
+	if parts[0] == "" {
+		return &davFile{
+			info:    davDirInfo{name: "/"},
+			readdir: func() ([]fs.FileInfo, error) { return d.objects(ctx) },
+		}, nil
+	}
+	id, err := url.PathUnescape(parts[0])
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	obj, err := d.root.NewObject(ctx, id, ocfl.ObjectMustExist())
+	if err != nil {
+		return nil, fmt.Errorf("reading object %q: %w", id, err)
+	}
+	if len(parts) == 1 {
+		return &davFile{
+			info:    davDirInfo{name: parts[0], modTime: obj.Version(0).Created()},
+			readdir: func() ([]fs.FileInfo, error) { return versionInfos(obj), nil },
+		}, nil
+	}
+	vnum, err := parseVersion(parts[1], obj.Head())
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	fsys, err := obj.VersionFS(ctx, vnum.Num())
+	if err != nil {
+		return nil, err
+	}
+	logical := "."
+	if len(parts) == 3 {
+		logical = parts[2]
+	}
+	f, err := fsys.Open(logical)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if logical == "." {
+		// the version directory is named for the version in the request path.
+		info = davDirInfo{name: parts[1], modTime: info.ModTime()}
+	}
+	if dir, ok := f.(fs.ReadDirFile); ok && info.IsDir() {
+		return &davFile{
+			info:   info,
+			closer: dir,
+			readdir: func() ([]fs.FileInfo, error) {
+				entries, err := dir.ReadDir(-1)
+				if err != nil {
+					return nil, err
+				}
+				infos := make([]fs.FileInfo, len(entries))
+				for i, e := range entries {
+					if infos[i], err = e.Info(); err != nil {
+						return nil, err
+					}
+				}
+				return infos, nil
+			},
+		}, nil
+	}
+	return &davFile{
+		info: info,
+		content: &seekFile{
+			file: f,
+			size: info.Size(),
+			open: func() (fs.File, error) { return fsys.Open(logical) },
+		},
+	}, nil
+}
+
+// objects returns directory infos for objects in the storage root.
+func (d *davFS) objects(ctx context.Context) ([]fs.FileInfo, error) {
+	var infos []fs.FileInfo
+	for obj, err := range d.root.Objects(ctx) {
+		if err != nil {
+			if errors.Is(err, ocflfs.ErrOpUnsupported) {
+				d.logger.Debug("storage root objects can't be listed", "err", err)
+				return nil, nil
+			}
+			return nil, err
+		}
+		infos = append(infos, davDirInfo{
+			name:    escapeID(obj.ID()),
+			modTime: obj.Version(0).Created(),
+		})
+	}
+	return infos, nil
+}
+
+// versionInfos returns directory infos for the object's versions and "head".
+func versionInfos(obj *ocfl.Object) []fs.FileInfo {
+	infos := []fs.FileInfo{davDirInfo{name: "head", modTime: obj.Version(0).Created()}}
+	for _, vnum := range obj.Head().Lineage() {
+		infos = append(infos, davDirInfo{
+			name:    vnum.String(),
+			modTime: obj.Version(vnum.Num()).Created(),
+		})
+	}
+	return infos
+}
+
+// escapeID escapes "%" and "/" in object IDs so they can be used as a path
+// segment; they are unescaped with url.PathUnescape.
+func escapeID(id string) string {
+	return strings.NewReplacer("%", "%25", "/", "%2F").Replace(id)
+}
+
+// davFile implements webdav.File for files (with content) and directories
+// (with readdir).
+type davFile struct {
+	info    fs.FileInfo
+	content *seekFile
+	closer  io.Closer
+	readdir func() ([]fs.FileInfo, error)
+	entries []fs.FileInfo
+	read    bool // readdir was called
+}
+
+var _ webdav.File = (*davFile)(nil)
+
+func (f *davFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (f *davFile) Read(p []byte) (int, error) {
+	if f.content == nil {
+		return 0, &fs.PathError{Op: "read", Path: f.info.Name(), Err: errors.New("is a directory")}
+	}
+	return f.content.Read(p)
+}
+
+func (f *davFile) Seek(offset int64, whence int) (int64, error) {
+	if f.content == nil {
+		return 0, &fs.PathError{Op: "seek", Path: f.info.Name(), Err: errors.New("is a directory")}
+	}
+	return f.content.Seek(offset, whence)
+}
+
+func (f *davFile) Write([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "write", Path: f.info.Name(), Err: fs.ErrPermission}
+}
+
+func (f *davFile) Readdir(count int) ([]fs.FileInfo, error) {
+	if f.readdir == nil {
+		return nil, &fs.PathError{Op: "readdir", Path: f.info.Name(), Err: errors.New("not a directory")}
+	}
+	if !f.read {
+		entries, err := f.readdir()
+		if err != nil {
+			return nil, err
+		}
+		f.entries = entries
+		f.read = true
+	}
+	if count <= 0 {
+		entries := f.entries
+		f.entries = nil
+		return entries, nil
+	}
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+	count = min(count, len(f.entries))
+	entries := f.entries[:count]
+	f.entries = f.entries[count:]
+	return entries, nil
+}
+
+func (f *davFile) Close() error {
+	switch {
+	case f.content != nil:
+		return f.content.Close()
+	case f.closer != nil:
+		return f.closer.Close()
+	}
+	return nil
+}
+
+// davDirInfo is fs.FileInfo for directories that aren't in an object's
+// logical state: the storage root, objects, and versions.
+type davDirInfo struct {
+	name    string
+	modTime time.Time
+}
+
+func (i davDirInfo) Name() string       { return i.name }
+func (i davDirInfo) Size() int64        { return 0 }
+func (i davDirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0555 }
+func (i davDirInfo) ModTime() time.Time { return i.modTime }
+func (i davDirInfo) IsDir() bool        { return true }
+func (i davDirInfo) Sys() any           { return nil }
@@ -0,0 +1,84 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-go"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/httpfs"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/server"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/testutil"
+)
+
+const davObjectPath = "/ark:123%252Fabc"
+
+func TestWebDAV(t *testing.T) {
+	ctx := context.Background()
+	_, fixtures := testutil.TempDirTestData(t, rootFixture)
+	root, err := ocfl.NewRoot(ctx, ocflfs.DirFS(fixtures[0]), ".")
+	be.NilErr(t, err)
+	srv := httptest.NewServer(server.NewWebDAV(root, nil))
+	defer srv.Close()
+
+	t.Run("list objects", func(t *testing.T) {
+		resp, body := do(t, "PROPFIND", srv.URL+"/", "", nil, http.Header{"Depth": {"1"}})
+		be.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+		be.In(t, "<D:href>"+davObjectPath+"/</D:href>", body)
+	})
+	t.Run("list versions", func(t *testing.T) {
+		resp, body := do(t, "PROPFIND", srv.URL+davObjectPath+"/", "", nil, http.Header{"Depth": {"1"}})
+		be.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+		be.In(t, davObjectPath+"/head/</D:href>", body)
+		be.In(t, davObjectPath+"/v1/</D:href>", body)
+	})
+	t.Run("version files", func(t *testing.T) {
+		resp, body := do(t, "PROPFIND", srv.URL+davObjectPath+"/v1/", "", nil, http.Header{"Depth": {"1"}})
+		be.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+		be.In(t, davObjectPath+"/v1/a_file.txt</D:href>", body)
+		be.In(t, "<D:getcontentlength>20</D:getcontentlength>", body)
+		be.In(t, "<D:getlastmodified>", body)
+	})
+	t.Run("get file", func(t *testing.T) {
+		resp, body := get(t, srv.URL+davObjectPath+"/head/a_file.txt", nil)
+		be.Equal(t, http.StatusOK, resp.StatusCode)
+		be.Equal(t, fileContent, body)
+		resp, body = get(t, srv.URL+davObjectPath+"/head/a_file.txt", http.Header{"Range": {"bytes=7-"}})
+		be.Equal(t, http.StatusPartialContent, resp.StatusCode)
+		be.Equal(t, fileContent[7:], body)
+	})
+	t.Run("not found", func(t *testing.T) {
+		resp, _ := get(t, srv.URL+"/missing/head/a_file.txt", nil)
+		be.Equal(t, http.StatusNotFound, resp.StatusCode)
+		resp, _ = get(t, srv.URL+davObjectPath+"/v2/a_file.txt", nil)
+		be.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+	t.Run("read-only", func(t *testing.T) {
+		for _, method := range []string{http.MethodPut, http.MethodDelete, "MKCOL", "MOVE", "COPY", "PROPPATCH", "LOCK"} {
+			resp, _ := do(t, method, srv.URL+davObjectPath+"/head/new.txt", "", strings.NewReader("content"), nil)
+			be.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+		}
+		resp, _ := do(t, http.MethodOptions, srv.URL+"/", "", nil, nil)
+		be.Equal(t, "OPTIONS, GET, HEAD, PROPFIND", resp.Header.Get("Allow"))
+	})
+}
+
+func TestWebDAVHTTPRoot(t *testing.T) {
+	ctx := context.Background()
+	fixtures := httptest.NewServer(http.FileServer(http.FS(testutil.TestDataFS())))
+	defer fixtures.Close()
+	root, err := ocfl.NewRoot(ctx, httpfs.New(fixtures.URL+"/"+rootFixture), ".")
+	be.NilErr(t, err)
+	srv := httptest.NewServer(server.NewWebDAV(root, nil))
+	defer srv.Close()
+	// objects can't be listed, but they can be accessed by id
+	resp, _ := do(t, "PROPFIND", srv.URL+"/", "", nil, http.Header{"Depth": {"1"}})
+	be.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+	resp, body := get(t, srv.URL+davObjectPath+"/v1/a_file.txt", nil)
+	be.Equal(t, http.StatusOK, resp.StatusCode)
+	be.Equal(t, fileContent, body)
+}
@@ -0,0 +1,572 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"mime"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/srerickson/ocfl-go"
+	"github.com/srerickson/ocfl-go/digest"
+
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/diff"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/stage"
+)
+
+var (
+	errBadRequest     = errors.New("bad request")
+	errConflict       = errors.New("conflict")
+	errDigestMismatch = errors.New("uploaded content does not match digest")
+)
+
+// digest algorithm names used in Digest and Content-Digest headers
+var httpDigestAlgs = map[string]string{
+	"md5":     "md5",
+	"sha":     "sha1",
+	"sha-1":   "sha1",
+	"sha-256": "sha256",
+	"sha-512": "sha512",
+}
+
+// CommitFunc creates a new version of obj using the stage.
+type CommitFunc func(ctx context.Context, obj *ocfl.Object, stage *ocfl.Stage, msg string, user ocfl.User) error
+
+// writeConfig configures the endpoints for updating objects
+type writeConfig struct {
+	stageDir string
+	tokens   Tokens
+	commit   CommitFunc
+	locks    sync.Map // stage ID -> *sync.Mutex
+	objLocks sync.Map // object ID -> *sync.Mutex
+}
+
+// WithWrites is an option that enables endpoints for creating stages,
+// uploading files, and committing new object versions. Stages and uploaded
+// files are stored in stageDir. Requests must include a bearer token that
+// tokens authorizes for the stage's object. Stages are committed using commit.
+func WithWrites(stageDir string, tokens Tokens, commit CommitFunc) Option {
+	return func(s *Server) {
+		s.writes = &writeConfig{
+			stageDir: stageDir,
+			tokens:   tokens,
+			commit:   commit,
+		}
+	}
+}
+
+// DefaultMaxUploadSize is the default limit for the size of upload request
+// bodies.
+const DefaultMaxUploadSize int64 = 10 << 30
+
+// WithMaxUploadSize sets the maximum size of request bodies for file uploads.
+// If size is zero or negative, uploads aren't limited.
+func WithMaxUploadSize(size int64) Option {
+	return func(s *Server) {
+		s.maxUploadSize = size
+	}
+}
+
+// stageInfo is a stage in write API responses
+type stageInfo struct {
+	Stage           string       `json:"stage"`
+	ObjectID        string       `json:"object_id"`
+	NextHead        string       `json:"next_head"`
+	DigestAlgorithm string       `json:"digest_algorithm"`
+	State           ocfl.PathMap `json:"state"`
+	Errors          []string     `json:"errors"`
+}
+
+// uploadResult is an uploaded file in write API responses
+type uploadResult struct {
+	Path   string `json:"path"`
+	Digest string `json:"digest"`
+}
+
+// newStageRequest is the request body for creating a stage
+type newStageRequest struct {
+	ObjectID        string `json:"object_id"`
+	DigestAlgorithm string `json:"digest_algorithm"`
+}
+
+// commitRequest is the request body for committing a stage
+type commitRequest struct {
+	Message string    `json:"message"`
+	User    ocfl.User `json:"user"`
+}
+
+func (s *Server) writeRoutes() {
+	s.mux.HandleFunc("POST /stages/{$}", s.createStage)
+	s.mux.HandleFunc("GET /stages/{stage}", s.showStage)
+	s.mux.HandleFunc("DELETE /stages/{stage}", s.deleteStage)
+	s.mux.HandleFunc("PUT /stages/{stage}/files/{path...}", s.putFile)
+	s.mux.HandleFunc("POST /stages/{stage}/files/{path...}", s.postFiles)
+	s.mux.HandleFunc("DELETE /stages/{stage}/files/{path...}", s.removeFiles)
+	s.mux.HandleFunc("GET /stages/{stage}/diff", s.stageDiff)
+	s.mux.HandleFunc("POST /stages/{stage}/commit", s.commitStage)
+}
+
+func (s *Server) createStage(w http.ResponseWriter, r *http.Request) {
+	var req newStageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.apiError(w, r, fmt.Errorf("%w: decoding request body: %w", errBadRequest, err))
+		return
+	}
+	if req.ObjectID == "" {
+		s.apiError(w, r, fmt.Errorf("%w: object_id is required", errBadRequest))
+		return
+	}
+	req.DigestAlgorithm = cmp.Or(req.DigestAlgorithm, digest.SHA512.ID())
+	if !slices.Contains([]string{digest.SHA512.ID(), digest.SHA256.ID()}, req.DigestAlgorithm) {
+		s.apiError(w, r, fmt.Errorf("%w: digest_algorithm must be sha512 or sha256", errBadRequest))
+		return
+	}
+	if err := s.writes.tokens.authorize(r, req.ObjectID); err != nil {
+		s.apiError(w, r, err)
+		return
+	}
+	obj, err := s.root.NewObject(r.Context(), req.ObjectID)
+	if err != nil {
+		s.apiError(w, r, fmt.Errorf("reading object %q: %w", req.ObjectID, err))
+		return
+	}
+	stageFile, err := stage.NewStageFile(obj, req.DigestAlgorithm)
+	if err != nil {
+		s.apiError(w, r, err)
+		return
+	}
+	id := rand.Text()
+	if err := os.MkdirAll(filepath.Join(s.writes.stageDir, id, "content"), 0755); err != nil {
+		s.apiError(w, r, err)
+		return
+	}
+	if err := stageFile.Write(s.stageFilePath(id)); err != nil {
+		s.apiError(w, r, err)
+		return
+	}
+	s.logger.Info("stage created", "stage", id, "object_id", stageFile.ID)
+	w.Header().Set("Location", "/stages/"+id)
+	writeJSON(w, http.StatusCreated, newStageInfo(id, stageFile))
+}
+
+func (s *Server) showStage(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("stage")
+	stageFile, err := s.authorizedStage(r, id)
+	if err != nil {
+		s.apiError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newStageInfo(id, stageFile))
+}
+
+func (s *Server) deleteStage(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("stage")
+	unlock := s.lockStage(id)
+	defer unlock()
+	if _, err := s.authorizedStage(r, id); err != nil {
+		s.apiError(w, r, err)
+		return
+	}
+	if err := os.RemoveAll(filepath.Join(s.writes.stageDir, id)); err != nil {
+		s.apiError(w, r, err)
+		return
+	}
+	s.writes.locks.Delete(id)
+	s.logger.Info("stage deleted", "stage", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) putFile(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("stage")
+	name := r.PathValue("path")
+	if name == "" || !fs.ValidPath(name) {
+		s.apiError(w, r, fmt.Errorf("%w: invalid file path: %q", errBadRequest, name))
+		return
+	}
+	if _, err := s.authorizedStage(r, id); err != nil {
+		s.apiError(w, r, err)
+		return
+	}
+	result, err := s.upload(id, name, s.limitBody(w, r), r.Header)
+	if err != nil {
+		s.apiError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, result)
+}
+
+// postFiles adds files from a multipart/form-data request. Files are added to
+// the directory in the request path using the file names in the form.
+func (s *Server) postFiles(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("stage")
+	dir := cmp.Or(strings.TrimSuffix(r.PathValue("path"), "/"), ".")
+	if !fs.ValidPath(dir) {
+		s.apiError(w, r, fmt.Errorf("%w: invalid directory path: %q", errBadRequest, dir))
+		return
+	}
+	if _, err := s.authorizedStage(r, id); err != nil {
+		s.apiError(w, r, err)
+		return
+	}
+	r.Body = s.limitBody(w, r)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		s.apiError(w, r, fmt.Errorf("%w: %w", errBadRequest, err))
+		return
+	}
+	results := []uploadResult{}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			s.apiError(w, r, fmt.Errorf("%w: reading multipart body: %w", errBadRequest, err))
+			return
+		}
+		fileName := partFileName(part.Header)
+		if fileName == "" {
+			part.Close()
+			continue
+		}
+		name := path.Join(dir, fileName)
+		if !fs.ValidPath(name) {
+			part.Close()
+			s.apiError(w, r, fmt.Errorf("%w: invalid file name: %q", errBadRequest, fileName))
+			return
+		}
+		result, err := s.upload(id, name, part, http.Header(part.Header))
+		part.Close()
+		if err != nil {
+			s.apiError(w, r, err)
+			return
+		}
+		results = append(results, *result)
+	}
+	writeJSON(w, http.StatusCreated, results)
+}
+
+func (s *Server) removeFiles(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("stage")
+	name := cmp.Or(strings.TrimSuffix(r.PathValue("path"), "/"), ".")
+	unlock := s.lockStage(id)
+	defer unlock()
+	stageFile, err := s.authorizedStage(r, id)
+	if err != nil {
+		s.apiError(w, r, err)
+		return
+	}
+	recursive := r.URL.Query().Get("recursive") == "true"
+	if err := stageFile.Remove(name, recursive); err != nil {
+		s.apiError(w, r, err)
+		return
+	}
+	if err := stageFile.Write(s.stageFilePath(id)); err != nil {
+		s.apiError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newStageInfo(id, stageFile))
+}
+
+func (s *Server) stageDiff(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("stage")
+	stageFile, err := s.authorizedStage(r, id)
+	if err != nil {
+		s.apiError(w, r, err)
+		return
+	}
+	obj, err := s.root.NewObject(r.Context(), stageFile.ID)
+	if err != nil {
+		s.apiError(w, r, err)
+		return
+	}
+	baseState := ocfl.PathMap{}
+	if obj.Exists() {
+		baseState = obj.Version(0).State().PathMap()
+	}
+	result, err := diff.Diff(baseState, stageFile.NextState)
+	if err != nil {
+		s.apiError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) commitStage(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("stage")
+	var req commitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.apiError(w, r, fmt.Errorf("%w: decoding request body: %w", errBadRequest, err))
+		return
+	}
+	if req.User.Name == "" {
+		s.apiError(w, r, fmt.Errorf("%w: user name is required", errBadRequest))
+		return
+	}
+	unlock := s.lockStage(id)
+	defer unlock()
+	stageFile, err := s.authorizedStage(r, id)
+	if err != nil {
+		s.apiError(w, r, err)
+		return
+	}
+	// stages for the same object are committed one at a time, so the head
+	// version checked below is the one the new version is added to.
+	unlockObj := s.lockObject(stageFile.ID)
+	defer unlockObj()
+	obj, err := s.root.NewObject(r.Context(), stageFile.ID)
+	if err != nil {
+		s.apiError(w, r, err)
+		return
+	}
+	next := ocfl.V(1)
+	if obj.Exists() {
+		if next, err = obj.Head().Next(); err != nil {
+			s.apiError(w, r, err)
+			return
+		}
+	}
+	if next.Num() != stageFile.NextHead.Num() {
+		s.apiError(w, r, fmt.Errorf("%w: object was updated after the stage was created", errConflict))
+		return
+	}
+	ocflStage, err := stageFile.Stage()
+	if err != nil {
+		s.apiError(w, r, fmt.Errorf("%w: stage has errors: %w", errBadRequest, err))
+		return
+	}
+	if err := s.writes.commit(r.Context(), obj, ocflStage, req.Message, req.User); err != nil {
+		s.apiError(w, r, err)
+		return
+	}
+	if err := os.RemoveAll(filepath.Join(s.writes.stageDir, id)); err != nil {
+		s.logger.Error("removing stage", "stage", id, "err", err)
+	}
+	s.writes.locks.Delete(id)
+	writeJSON(w, http.StatusCreated, newObjectSummary(obj))
+}
+
+// upload saves the content from body to the stage's content directory and
+// adds it to the stage as name. If the header includes Content-Digest or
+// Digest values, they are checked against the content.
+func (s *Server) upload(id string, name string, body io.Reader, header http.Header) (*uploadResult, error) {
+	want, err := requestDigests(header)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(filepath.Join(s.writes.stageDir, id, "content"), "upload-*")
+	if err != nil {
+		return nil, err
+	}
+	localPath := f.Name()
+	algs := digest.DefaultRegistry().GetAny(slices.Collect(maps.Keys(want))...)
+	digester := digest.NewMultiDigester(algs...)
+	_, err = io.Copy(io.MultiWriter(f, digester), body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		for alg, sum := range want {
+			if digester.Sum(alg) != sum {
+				err = fmt.Errorf("%w: %s: %s", errDigestMismatch, name, alg)
+				break
+			}
+		}
+	}
+	if err != nil {
+		os.Remove(localPath)
+		return nil, err
+	}
+	unlock := s.lockStage(id)
+	defer unlock()
+	stageFile, err := s.readStage(id)
+	if err == nil {
+		err = addUpload(stageFile, name, localPath)
+	}
+	if err == nil {
+		err = stageFile.Write(s.stageFilePath(id))
+	}
+	if err != nil {
+		os.Remove(localPath)
+		return nil, err
+	}
+	if content := stageFile.LocalContent[stageFile.NextState[name]]; content == nil || content.Path != localPath {
+		// the content was already staged or committed
+		os.Remove(localPath)
+	}
+	s.logger.Info("file uploaded", "stage", id, "path", name)
+	return &uploadResult{Path: name, Digest: stageFile.NextState[name]}, nil
+}
+
+// addUpload adds the uploaded file at localPath to the stage as name.
+func addUpload(stageFile *stage.StageFile, name string, localPath string) error {
+	for p := range stageFile.NextState {
+		if strings.HasPrefix(p, name+"/") || strings.HasPrefix(name, p+"/") {
+			return fmt.Errorf("%w: can't add %q because of conflict with %q", errConflict, name, p)
+		}
+	}
+	return stageFile.AddFile(localPath, stage.AddAs(name))
+}
+
+// authorizedStage reads the stage with the id and checks that the request is
+// authorized to update the stage's object.
+func (s *Server) authorizedStage(r *http.Request, id string) (*stage.StageFile, error) {
+	stageFile, err := s.readStage(id)
+	if err != nil {
+		// don't reveal stages to unauthorized requests
+		if authErr := s.writes.tokens.authorize(r, ""); errors.Is(authErr, errUnauthorized) {
+			return nil, authErr
+		}
+		return nil, err
+	}
+	if err := s.writes.tokens.authorize(r, stageFile.ID); err != nil {
+		return nil, err
+	}
+	return stageFile, nil
+}
+
+func (s *Server) readStage(id string) (*stage.StageFile, error) {
+	if !validStageID(id) {
+		return nil, fmt.Errorf("stage %q: %w", id, fs.ErrNotExist)
+	}
+	stageFile, err := stage.ReadStageFile(s.stageFilePath(id))
+	if err != nil {
+		return nil, fmt.Errorf("reading stage %q: %w", id, err)
+	}
+	return stageFile, nil
+}
+
+func (s *Server) stageFilePath(id string) string {
+	return filepath.Join(s.writes.stageDir, id, "stage.json")
+}
+
+// limitBody returns the request body, limited to the server's maximum upload
+// size.
+func (s *Server) limitBody(w http.ResponseWriter, r *http.Request) io.ReadCloser {
+	if s.maxUploadSize <= 0 {
+		return r.Body
+	}
+	return http.MaxBytesReader(w, r.Body, s.maxUploadSize)
+}
+
+// lockStage locks the stage with the id, returning a function to unlock it.
+// Locks for deleted and committed stages are removed from the map while they
+// are held: requests already waiting for the lock fail because the stage no
+// longer exists.
+func (s *Server) lockStage(id string) func() {
+	if !validStageID(id) {
+		return func() {}
+	}
+	lock, _ := s.writes.locks.LoadOrStore(id, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	return lock.(*sync.Mutex).Unlock
+}
+
+// lockObject locks the object with the id, returning a function to unlock it.
+func (s *Server) lockObject(id string) func() {
+	lock, _ := s.writes.objLocks.LoadOrStore(id, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	return lock.(*sync.Mutex).Unlock
+}
+
+func newStageInfo(id string, stageFile *stage.StageFile) stageInfo {
+	info := stageInfo{
+		Stage:           id,
+		ObjectID:        stageFile.ID,
+		NextHead:        stageFile.NextHead.String(),
+		DigestAlgorithm: stageFile.AlgID,
+		State:           stageFile.NextState,
+		Errors:          []string{},
+	}
+	for err := range stageFile.StateErrors() {
+		info.Errors = append(info.Errors, err.Error())
+	}
+	for err := range stageFile.ContentErrors() {
+		info.Errors = append(info.Errors, err.Error())
+	}
+	return info
+}
+
+// validStageID returns true if id could have been created by createStage.
+func validStageID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'A' && c <= 'Z' || c >= '2' && c <= '7') {
+			return false
+		}
+	}
+	return true
+}
+
+// partFileName returns the file name for a multipart form part, including any
+// directory names.
+func partFileName(header textproto.MIMEHeader) string {
+	_, params, err := mime.ParseMediaType(header.Get("Content-Disposition"))
+	if err != nil {
+		return ""
+	}
+	return params["filename"]
+}
+
+// requestDigests returns the hex-encoded digests from the header's
+// Content-Digest (RFC 9530) and Digest (RFC 3230) values. Digests using
+// unsupported algorithms are ignored, but it is an error if the header has
+// digests and none are supported.
+func requestDigests(header http.Header) (digest.Set, error) {
+	digests := digest.Set{}
+	var found bool
+	for _, key := range []string{"Content-Digest", "Digest"} {
+		for _, val := range header.Values(key) {
+			for item := range strings.SplitSeq(val, ",") {
+				found = true
+				name, encoded, ok := strings.Cut(strings.TrimSpace(item), "=")
+				if !ok {
+					return nil, fmt.Errorf("%w: invalid %s header: %q", errBadRequest, key, val)
+				}
+				alg, supported := httpDigestAlgs[strings.ToLower(name)]
+				if !supported {
+					continue
+				}
+				if key == "Content-Digest" {
+					encoded, _, _ = strings.Cut(encoded, ";")
+					encoded = strings.Trim(encoded, ":")
+				}
+				sum, err := base64.StdEncoding.DecodeString(encoded)
+				if err != nil {
+					return nil, fmt.Errorf("%w: invalid %s header: %q", errBadRequest, key, val)
+				}
+				digests[alg] = hex.EncodeToString(sum)
+			}
+		}
+	}
+	if found && len(digests) == 0 {
+		return nil, fmt.Errorf("%w: no supported digest algorithms in request", errBadRequest)
+	}
+	return digests, nil
+}
+
+// writeJSON writes v as the response body with the status code.
+func writeJSON(w http.ResponseWriter, status int, v any) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
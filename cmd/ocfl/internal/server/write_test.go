@@ -0,0 +1,289 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-go"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-go/fs/local"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/server"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/testutil"
+)
+
+const (
+	adminToken  = "admin-token"
+	ingestToken = "ingest-token"
+)
+
+func TestServerWrites(t *testing.T) {
+	ctx := context.Background()
+	tmp, fixtures := testutil.TempDirTestData(t, rootFixture)
+	fsys, err := local.NewFS(fixtures[0])
+	be.NilErr(t, err)
+	root, err := ocfl.NewRoot(ctx, fsys, ".")
+	be.NilErr(t, err)
+	tokensFile := filepath.Join(tmp, "tokens.txt")
+	be.NilErr(t, os.WriteFile(tokensFile, []byte(
+		"# test tokens\n"+
+			adminToken+" *\n"+
+			ingestToken+" new:*\n",
+	), 0644))
+	tokens, err := server.ReadTokens(tokensFile)
+	be.NilErr(t, err)
+	commit := func(ctx context.Context, obj *ocfl.Object, stage *ocfl.Stage, msg string, user ocfl.User) error {
+		_, err := obj.Update(ctx, stage, msg, user)
+		return err
+	}
+	srv := httptest.NewServer(server.New(root,
+		server.WithWrites(filepath.Join(tmp, "stages"), tokens, commit)))
+	defer srv.Close()
+
+	t.Run("new object", func(t *testing.T) {
+		stageURL := newStage(t, srv.URL, ingestToken, "new:1")
+		// single file upload with digest
+		content := []byte("new content")
+		sum := sha256.Sum256(content)
+		resp, body := do(t, http.MethodPut, stageURL+"/files/a/b.txt", ingestToken, bytes.NewReader(content),
+			http.Header{"Content-Digest": {"sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"}})
+		be.Equal(t, http.StatusCreated, resp.StatusCode)
+		be.In(t, `"path": "a/b.txt"`, body)
+		// multipart upload
+		form := &bytes.Buffer{}
+		mpw := multipart.NewWriter(form)
+		part, err := mpw.CreateFormFile("file", "c.txt")
+		be.NilErr(t, err)
+		part.Write([]byte("c content"))
+		part, err = mpw.CreateFormFile("file", "d/e.txt")
+		be.NilErr(t, err)
+		part.Write([]byte("e content"))
+		be.NilErr(t, mpw.Close())
+		resp, body = do(t, http.MethodPost, stageURL+"/files/dir", ingestToken, form,
+			http.Header{"Content-Type": {mpw.FormDataContentType()}})
+		be.Equal(t, http.StatusCreated, resp.StatusCode)
+		be.In(t, `"path": "dir/c.txt"`, body)
+		be.In(t, `"path": "dir/d/e.txt"`, body)
+		// stage diff
+		var changes struct {
+			Added []string `json:"added"`
+		}
+		resp, body = do(t, http.MethodGet, stageURL+"/diff", ingestToken, nil, nil)
+		be.Equal(t, http.StatusOK, resp.StatusCode)
+		be.NilErr(t, json.Unmarshal([]byte(body), &changes))
+		be.DeepEqual(t, []string{"a/b.txt", "dir/c.txt", "dir/d/e.txt"}, changes.Added)
+		// commit
+		resp, body = do(t, http.MethodPost, stageURL+"/commit", ingestToken,
+			strings.NewReader(`{"message": "first", "user": {"name": "Tester"}}`), nil)
+		be.Equal(t, http.StatusCreated, resp.StatusCode)
+		be.In(t, `"head": "v1"`, body)
+		resp, body = get(t, srv.URL+"/objects/new:1/versions/head/dir/d/e.txt", nil)
+		be.Equal(t, http.StatusOK, resp.StatusCode)
+		be.Equal(t, "e content", body)
+		// stage is removed after commit
+		resp, _ = do(t, http.MethodGet, stageURL, ingestToken, nil, nil)
+		be.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+	t.Run("update and remove", func(t *testing.T) {
+		stageURL := newStage(t, srv.URL, adminToken, "ark:123/abc")
+		resp, body := do(t, http.MethodDelete, stageURL+"/files/a_file.txt", adminToken, nil, nil)
+		be.Equal(t, http.StatusOK, resp.StatusCode)
+		be.In(t, `"state": {}`, body)
+		resp, _ = do(t, http.MethodPut, stageURL+"/files/new.txt", adminToken, strings.NewReader("new"), nil)
+		be.Equal(t, http.StatusCreated, resp.StatusCode)
+		resp, _ = do(t, http.MethodPost, stageURL+"/commit", adminToken,
+			strings.NewReader(`{"message": "replace", "user": {"name": "Tester"}}`), nil)
+		be.Equal(t, http.StatusCreated, resp.StatusCode)
+		resp, _ = get(t, srv.URL+objectURL+"/versions/v2/new.txt", nil)
+		be.Equal(t, http.StatusOK, resp.StatusCode)
+		resp, _ = get(t, srv.URL+objectURL+"/versions/v2/a_file.txt", nil)
+		be.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+	t.Run("digest mismatch", func(t *testing.T) {
+		stageURL := newStage(t, srv.URL, ingestToken, "new:2")
+		resp, _ := do(t, http.MethodPut, stageURL+"/files/a.txt", ingestToken, strings.NewReader("content"),
+			http.Header{"Digest": {"SHA-256=" + base64.StdEncoding.EncodeToString(make([]byte, 32))}})
+		be.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp, body := do(t, http.MethodGet, stageURL, ingestToken, nil, nil)
+		be.Equal(t, http.StatusOK, resp.StatusCode)
+		be.In(t, `"state": {}`, body)
+		entries, err := os.ReadDir(filepath.Join(tmp, "stages", filepath.Base(stageURL), "content"))
+		be.NilErr(t, err)
+		be.Equal(t, 0, len(entries))
+	})
+	t.Run("unsupported digest", func(t *testing.T) {
+		stageURL := newStage(t, srv.URL, ingestToken, "new:3")
+		resp, _ := do(t, http.MethodPut, stageURL+"/files/a.txt", ingestToken, strings.NewReader("content"),
+			http.Header{"Content-Digest": {"unixsum=:MTIz:"}})
+		be.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+	t.Run("path conflict", func(t *testing.T) {
+		stageURL := newStage(t, srv.URL, ingestToken, "new:4")
+		resp, _ := do(t, http.MethodPut, stageURL+"/files/a", ingestToken, strings.NewReader("content"), nil)
+		be.Equal(t, http.StatusCreated, resp.StatusCode)
+		resp, _ = do(t, http.MethodPut, stageURL+"/files/a/b", ingestToken, strings.NewReader("content"), nil)
+		be.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+	t.Run("stale stage", func(t *testing.T) {
+		stage1 := newStage(t, srv.URL, ingestToken, "new:5")
+		stage2 := newStage(t, srv.URL, ingestToken, "new:5")
+		for _, stageURL := range []string{stage1, stage2} {
+			resp, _ := do(t, http.MethodPut, stageURL+"/files/a.txt", ingestToken, strings.NewReader(stageURL), nil)
+			be.Equal(t, http.StatusCreated, resp.StatusCode)
+		}
+		commitBody := `{"message": "update", "user": {"name": "Tester"}}`
+		resp, _ := do(t, http.MethodPost, stage1+"/commit", ingestToken, strings.NewReader(commitBody), nil)
+		be.Equal(t, http.StatusCreated, resp.StatusCode)
+		resp, _ = do(t, http.MethodPost, stage2+"/commit", ingestToken, strings.NewReader(commitBody), nil)
+		be.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+	t.Run("concurrent commits", func(t *testing.T) {
+		// a slow commit gives the other request time to read the object's head
+		slowCommit := func(ctx context.Context, obj *ocfl.Object, stage *ocfl.Stage, msg string, user ocfl.User) error {
+			time.Sleep(100 * time.Millisecond)
+			return commit(ctx, obj, stage, msg, user)
+		}
+		slow := httptest.NewServer(server.New(root,
+			server.WithWrites(filepath.Join(tmp, "stages"), tokens, slowCommit)))
+		defer slow.Close()
+		stages := []string{
+			newStage(t, slow.URL, ingestToken, "new:9"),
+			newStage(t, slow.URL, ingestToken, "new:9"),
+		}
+		for _, stageURL := range stages {
+			resp, _ := do(t, http.MethodPut, stageURL+"/files/a.txt", ingestToken, strings.NewReader(stageURL), nil)
+			be.Equal(t, http.StatusCreated, resp.StatusCode)
+		}
+		codes := make([]int, len(stages))
+		var wg sync.WaitGroup
+		for i, stageURL := range stages {
+			wg.Go(func() {
+				req, err := http.NewRequest(http.MethodPost, stageURL+"/commit",
+					strings.NewReader(`{"message": "update", "user": {"name": "Tester"}}`))
+				if err != nil {
+					return
+				}
+				req.Header.Set("Authorization", "Bearer "+ingestToken)
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					return
+				}
+				resp.Body.Close()
+				codes[i] = resp.StatusCode
+			})
+		}
+		wg.Wait()
+		slices.Sort(codes)
+		be.AllEqual(t, []int{http.StatusCreated, http.StatusConflict}, codes)
+		resp, body := get(t, slow.URL+"/objects/new:9", nil)
+		be.Equal(t, http.StatusOK, resp.StatusCode)
+		be.In(t, "v1", body)
+	})
+	t.Run("upload too large", func(t *testing.T) {
+		limited := httptest.NewServer(server.New(root,
+			server.WithWrites(filepath.Join(tmp, "stages"), tokens, commit),
+			server.WithMaxUploadSize(10)))
+		defer limited.Close()
+		stageURL := newStage(t, limited.URL, ingestToken, "new:8")
+		resp, _ := do(t, http.MethodPut, stageURL+"/files/a.txt", ingestToken, strings.NewReader("more than ten bytes"), nil)
+		be.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+		form := &bytes.Buffer{}
+		mpw := multipart.NewWriter(form)
+		part, err := mpw.CreateFormFile("file", "b.txt")
+		be.NilErr(t, err)
+		part.Write([]byte("more than ten bytes"))
+		be.NilErr(t, mpw.Close())
+		resp, _ = do(t, http.MethodPost, stageURL+"/files/", ingestToken, form,
+			http.Header{"Content-Type": {mpw.FormDataContentType()}})
+		be.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+		resp, _ = do(t, http.MethodPut, stageURL+"/files/c.txt", ingestToken, strings.NewReader("small"), nil)
+		be.Equal(t, http.StatusCreated, resp.StatusCode)
+		resp, body := do(t, http.MethodGet, stageURL, ingestToken, nil, nil)
+		be.Equal(t, http.StatusOK, resp.StatusCode)
+		be.In(t, `"c.txt"`, body)
+		be.False(t, strings.Contains(body, `"a.txt"`))
+		entries, err := os.ReadDir(filepath.Join(tmp, "stages", filepath.Base(stageURL), "content"))
+		be.NilErr(t, err)
+		be.Equal(t, 1, len(entries))
+	})
+	t.Run("delete stage", func(t *testing.T) {
+		stageURL := newStage(t, srv.URL, ingestToken, "new:6")
+		resp, _ := do(t, http.MethodDelete, stageURL, ingestToken, nil, nil)
+		be.Equal(t, http.StatusNoContent, resp.StatusCode)
+		resp, _ = do(t, http.MethodGet, stageURL, ingestToken, nil, nil)
+		be.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+	t.Run("unauthorized", func(t *testing.T) {
+		body := `{"object_id": "new:7"}`
+		resp, _ := do(t, http.MethodPost, srv.URL+"/stages/", "", strings.NewReader(body), nil)
+		be.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		be.Equal(t, "Bearer", resp.Header.Get("WWW-Authenticate"))
+		resp, _ = do(t, http.MethodPost, srv.URL+"/stages/", "wrong-token", strings.NewReader(body), nil)
+		be.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		stageURL := newStage(t, srv.URL, adminToken, "new:7")
+		resp, _ = do(t, http.MethodGet, stageURL, "", nil, nil)
+		be.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+	t.Run("forbidden", func(t *testing.T) {
+		body := `{"object_id": "ark:123/abc"}`
+		resp, _ := do(t, http.MethodPost, srv.URL+"/stages/", ingestToken, strings.NewReader(body), nil)
+		be.Equal(t, http.StatusForbidden, resp.StatusCode)
+		stageURL := newStage(t, srv.URL, adminToken, "ark:123/abc")
+		resp, _ = do(t, http.MethodPut, stageURL+"/files/a.txt", ingestToken, strings.NewReader("content"), nil)
+		be.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+}
+
+func TestServerWritesDisabled(t *testing.T) {
+	ctx := context.Background()
+	_, fixtures := testutil.TempDirTestData(t, rootFixture)
+	root, err := ocfl.NewRoot(ctx, ocflfs.DirFS(fixtures[0]), ".")
+	be.NilErr(t, err)
+	srv := httptest.NewServer(server.New(root))
+	defer srv.Close()
+	resp, _ := do(t, http.MethodPost, srv.URL+"/stages/", adminToken, strings.NewReader(`{"object_id": "new:1"}`), nil)
+	be.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+// newStage creates a stage for the object and returns its URL
+func newStage(t *testing.T, srvURL string, token string, id string) string {
+	t.Helper()
+	reqBody, err := json.Marshal(map[string]string{"object_id": id})
+	be.NilErr(t, err)
+	resp, body := do(t, http.MethodPost, srvURL+"/stages/", token, bytes.NewReader(reqBody), nil)
+	be.Equal(t, http.StatusCreated, resp.StatusCode)
+	be.In(t, `"object_id": "`+id+`"`, body)
+	return srvURL + resp.Header.Get("Location")
+}
+
+func do(t *testing.T, method string, u string, token string, body io.Reader, header http.Header) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, u, body)
+	be.NilErr(t, err)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	be.NilErr(t, err)
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	be.NilErr(t, err)
+	return resp, string(respBody)
+}
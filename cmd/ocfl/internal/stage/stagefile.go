@@ -244,7 +244,13 @@ func (s *StageFile) AddDir(ctx context.Context, localDir string, opts ...AddOpti
 			Size:    result.Info.Size(),
 			Modtime: result.Info.ModTime(),
 		}
-		if err := s.add(logicalPath, localFile, result.Digests); err != nil {
+		// fixity digests (for the root's fixity_algorithms) are reported
+		// separately from the primary digest, but they are saved together in
+		// the stage file. The result's maps belong to the digester, so the
+		// digests are merged in a copy.
+		digests := maps.Clone(result.Digests)
+		maps.Copy(digests, result.Fixity)
+		if err := s.add(logicalPath, localFile, digests); err != nil {
 			return err
 		}
 	}
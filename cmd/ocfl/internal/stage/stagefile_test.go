@@ -2,6 +2,9 @@ package stage_test
 
 import (
 	"context"
+	"crypto/md5"
+	"crypto/sha512"
+	"encoding/hex"
 	"io/fs"
 	"os"
 	"path"
@@ -11,6 +14,7 @@ import (
 
 	"github.com/carlmjohnson/be"
 	"github.com/srerickson/ocfl-go"
+	"github.com/srerickson/ocfl-go/digest"
 	ocflfs "github.com/srerickson/ocfl-go/fs"
 	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/stage"
 	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/testutil"
@@ -66,6 +70,28 @@ func TestStageFile_AddDir(t *testing.T) {
 			be.NilErr(t, stageErrors(changes))
 		})
 
+		t.Run("with fixity", func(t *testing.T) {
+			changes, err := stage.NewStageFile(newObj, "sha512")
+			be.NilErr(t, err)
+			changes.FixityIDs = []string{"md5"}
+			err = changes.AddDir(ctx, contentFixture)
+			be.NilErr(t, err)
+			// the stage file is written and read back to check the saved digests
+			stageFile := filepath.Join(t.TempDir(), "stage.json")
+			be.NilErr(t, changes.Write(stageFile))
+			changes, err = stage.ReadStageFile(stageFile)
+			be.NilErr(t, err)
+			be.Nonzero(t, len(changes.NextState))
+			for name, primary := range changes.NextState {
+				content, err := os.ReadFile(filepath.Join(contentFixture, filepath.FromSlash(name)))
+				be.NilErr(t, err)
+				sha512Sum := sha512.Sum512(content)
+				md5Sum := md5.Sum(content)
+				be.Equal(t, hex.EncodeToString(sha512Sum[:]), primary)
+				be.DeepEqual(t, digest.Set{"md5": hex.EncodeToString(md5Sum[:])}, changes.GetFixity(primary))
+			}
+		})
+
 		t.Run("with as", func(t *testing.T) {
 			changes, err := stage.NewStageFile(newObj, "sha512")
 			be.NilErr(t, err)
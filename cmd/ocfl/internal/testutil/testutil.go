@@ -1,9 +1,12 @@
 package testutil
 
 import (
+	"archive/tar"
+	"archive/zip"
 	"embed"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"testing"
 )
@@ -41,3 +44,57 @@ func TempDirTestData(t *testing.T, subdirs ...string) (string, []string) {
 	}
 	return tmpDir, tmpSubs
 }
+
+// ArchiveDir writes the contents of the directory dir to a new zip or tar
+// file (depending on name's extension) at name. Files are stored in the
+// archive under prefix.
+func ArchiveDir(t *testing.T, dir string, name string, prefix string) {
+	t.Helper()
+	out, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	var (
+		zw *zip.Writer
+		tw *tar.Writer
+	)
+	if filepath.Ext(name) == ".zip" {
+		zw = zip.NewWriter(out)
+	} else {
+		tw = tar.NewWriter(out)
+	}
+	err = fs.WalkDir(os.DirFS(dir), ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(p)))
+		if err != nil {
+			return err
+		}
+		archiveName := path.Join(prefix, p)
+		if zw != nil {
+			w, err := zw.Create(archiveName)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(data)
+			return err
+		}
+		hdr := &tar.Header{Name: archiveName, Mode: 0o644, Size: int64(len(data)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		return err
+	})
+	if err == nil && zw != nil {
+		err = zw.Close()
+	}
+	if err == nil && tw != nil {
+		err = tw.Close()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,275 @@
+package run
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand/v2"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/srerickson/ocfl-go"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-go/fs/local"
+	"golang.org/x/sync/errgroup"
+)
+
+const auditHelp = "Validate objects that are new, changed, or not recently audited, and record results in an audit ledger"
+
+// auditExtension is the directory in the storage root's extensions directory
+// where the default audit ledger is kept.
+const auditExtension = "ocfl-tools-audit"
+
+const auditLedgerFile = "ledger.jsonl"
+
+type AuditCmd struct {
+	Ledger string        `name:"ledger" help:"path to a local audit ledger file. Defaults to a ledger in the storage root's extensions directory."`
+	MaxAge time.Duration `name:"max-age" default:"720h" help:"re-validate objects that haven't been audited within this duration"`
+	Sample float64       `name:"sample" default:"0" help:"percentage (0-100) of the remaining objects to re-validate at random"`
+	Jobs   int           `name:"jobs" short:"j" default:"0" help:"number of objects to validate concurrently. Defaults to the number of CPU cores."`
+	DryRun bool          `name:"dry-run" help:"report which objects would be validated without validating them"`
+}
+
+// auditReason describes why an object is validated in an audit.
+type auditReason string
+
+const (
+	auditUnaudited auditReason = "unaudited"
+	auditChanged   auditReason = "changed"
+	auditExpired   auditReason = "expired"
+	auditSampled   auditReason = "sampled"
+)
+
+// auditRecord is an entry in the audit ledger: the result of a full validation
+// of an object.
+type auditRecord struct {
+	ID              string    `json:"id"`
+	Path            string    `json:"path"`
+	Head            string    `json:"head"`
+	InventoryDigest string    `json:"inventory_digest"`
+	Validated       time.Time `json:"validated"`
+	Valid           bool      `json:"valid"`
+	Errors          []string  `json:"errors,omitempty"`
+}
+
+// auditLedger is an append-only log of auditRecords.
+type auditLedger struct {
+	fsys ocflfs.FS
+	name string
+	raw  []byte // existing ledger content
+
+	// last successful validation for each object ID
+	lastValid map[string]auditRecord
+
+	mx    sync.Mutex
+	added []auditRecord
+}
+
+func (cmd *AuditCmd) Run(g *globals) error {
+	// read files from the storage root, not the cache, to detect damage.
+	g.noCache = true
+	ctx := g.ctx
+	if cmd.Sample < 0 || cmd.Sample > 100 {
+		return errors.New("--sample must be between 0 and 100")
+	}
+	jobs := cmd.Jobs
+	if jobs < 1 {
+		jobs = runtime.NumCPU()
+	}
+	root, err := g.getRoot()
+	if err != nil {
+		return err
+	}
+	ledgerFS, ledgerName := root.FS(), path.Join(root.Path(), extensionsDir, auditExtension, auditLedgerFile)
+	if cmd.Ledger != "" {
+		absPath, err := filepath.Abs(cmd.Ledger)
+		if err != nil {
+			return err
+		}
+		ledgerFS, err = local.NewFS(filepath.Dir(absPath))
+		if err != nil {
+			return err
+		}
+		ledgerName = filepath.Base(absPath)
+	}
+	ledger, err := readAuditLedger(ctx, ledgerFS, ledgerName)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	reasons := map[auditReason]int{}
+	var (
+		total       int // number of objects in the root
+		covered     int // objects verified within max-age
+		passed      int // objects that passed validation in this audit
+		failed      int // objects that failed validation in this audit
+		countMx     sync.Mutex
+		grp, grpCtx = errgroup.WithContext(ctx)
+	)
+	grp.SetLimit(jobs)
+	for obj, err := range root.ObjectsBatch(ctx, jobs) {
+		if err != nil {
+			grp.Wait()
+			return fmt.Errorf("while listing objects in the storage root: %w", err)
+		}
+		total++
+		reason := cmd.auditReason(obj, ledger, now)
+		if reason == "" {
+			covered++
+			continue
+		}
+		reasons[reason]++
+		if cmd.DryRun {
+			g.logger.Info("would validate object", "object_id", obj.ID(), "reason", reason)
+			continue
+		}
+		grp.Go(func() error {
+			logger := g.logger.With("object_id", obj.ID())
+			result := ocfl.ValidateObject(grpCtx, obj.FS(), obj.Path(), ocfl.ValidationLogger(logger))
+			if err := grpCtx.Err(); err != nil {
+				return err
+			}
+			record := auditRecord{
+				ID:              obj.ID(),
+				Path:            obj.Path(),
+				Head:            obj.Head().String(),
+				InventoryDigest: obj.InventoryDigest(),
+				Validated:       time.Now().UTC(),
+				Valid:           result.Err() == nil,
+			}
+			for _, err := range result.Errors() {
+				record.Errors = append(record.Errors, err.Error())
+			}
+			ledger.add(record)
+			countMx.Lock()
+			defer countMx.Unlock()
+			if record.Valid {
+				passed++
+			} else {
+				failed++
+			}
+			return nil
+		})
+	}
+	waitErr := grp.Wait()
+	if !cmd.DryRun {
+		// results are saved even if the audit was interrupted
+		if err := ledger.save(ctx); err != nil {
+			return err
+		}
+	}
+	if waitErr != nil {
+		return waitErr
+	}
+	if cmd.DryRun {
+		fmt.Fprintln(g.stdout, "dry run: no objects were validated")
+	}
+	covered += passed
+	var audited int
+	for _, n := range reasons {
+		audited += n
+	}
+	fmt.Fprintf(g.stdout, "objects:  %d (%d audited: %d changed, %d expired, %d unaudited, %d sampled; %d failed)\n",
+		total, audited, reasons[auditChanged], reasons[auditExpired], reasons[auditUnaudited], reasons[auditSampled], failed)
+	coverage := 100.0
+	if total > 0 {
+		coverage = 100 * float64(covered) / float64(total)
+	}
+	fmt.Fprintf(g.stdout, "coverage: %.1f%% (%d of %d objects verified within %s)\n", coverage, covered, total, cmd.MaxAge)
+	fmt.Fprintf(g.stdout, "ledger:   %s\n", locationString(ledger.fsys, ledger.name))
+	if failed > 0 {
+		return fmt.Errorf("audit found %d object(s) with errors", failed)
+	}
+	return nil
+}
+
+// auditReason returns the reason obj should be validated, or an empty string
+// if it doesn't need to be.
+func (cmd *AuditCmd) auditReason(obj *ocfl.Object, ledger *auditLedger, now time.Time) auditReason {
+	last, ok := ledger.lastValid[obj.ID()]
+	switch {
+	case !ok:
+		return auditUnaudited
+	case last.InventoryDigest != obj.InventoryDigest():
+		return auditChanged
+	case now.Sub(last.Validated) > cmd.MaxAge:
+		return auditExpired
+	case cmd.Sample > 0 && rand.Float64()*100 < cmd.Sample:
+		return auditSampled
+	}
+	return ""
+}
+
+// readAuditLedger reads the audit ledger in fsys. The ledger doesn't need to
+// exist.
+func readAuditLedger(ctx context.Context, fsys ocflfs.FS, name string) (*auditLedger, error) {
+	ledger := &auditLedger{
+		fsys:      fsys,
+		name:      name,
+		lastValid: map[string]auditRecord{},
+	}
+	raw, err := ocflfs.ReadAll(ctx, fsys, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ledger, nil
+		}
+		return nil, fmt.Errorf("reading audit ledger: %w", err)
+	}
+	ledger.raw = raw
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	scanner.Buffer(nil, 1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var record auditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("reading audit ledger, line %d: %w", lineNum, err)
+		}
+		if !record.Valid {
+			continue
+		}
+		if prev, ok := ledger.lastValid[record.ID]; !ok || record.Validated.After(prev.Validated) {
+			ledger.lastValid[record.ID] = record
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading audit ledger: %w", err)
+	}
+	return ledger, nil
+}
+
+func (l *auditLedger) add(record auditRecord) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	l.added = append(l.added, record)
+}
+
+// save appends new records to the ledger. The ledger is rewritten because not
+// all backends support appending to files.
+func (l *auditLedger) save(ctx context.Context) error {
+	if len(l.added) == 0 {
+		return nil
+	}
+	buf := bytes.NewBuffer(l.raw)
+	if buf.Len() > 0 && !bytes.HasSuffix(l.raw, []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	enc := json.NewEncoder(buf)
+	for _, record := range l.added {
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+	}
+	if _, err := ocflfs.Write(ctx, l.fsys, l.name, buf); err != nil {
+		return fmt.Errorf("writing audit ledger: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package run_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/testutil"
+)
+
+func TestAudit(t *testing.T) {
+	tmpDir, fixtures := testutil.TempDirTestData(t,
+		`testdata/store-fixtures/1.0/good-stores/reg-extension-dir-root`,
+		`testdata/content-fixture`,
+	)
+	root := fixtures[0]
+	contentFixture := fixtures[1]
+	id := "ark:123/abc"
+
+	t.Run("default ledger", func(t *testing.T) {
+		args := []string{`audit`, `--root`, root}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "1 unaudited", stdout)
+			be.In(t, "coverage: 100.0%", stdout)
+		})
+		ledger := filepath.Join(root, "extensions", "ocfl-tools-audit", "ledger.jsonl")
+		_, err := os.Stat(ledger)
+		be.NilErr(t, err)
+		// second run doesn't validate anything
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "(0 audited", stdout)
+		})
+		// the ledger doesn't cause validation warnings
+		args = []string{`validate`, `--root`, root, `--fail-on-warnings`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.True(t, !strings.Contains(stderr, "ocfl-tools-audit"))
+		})
+	})
+
+	t.Run("local ledger", func(t *testing.T) {
+		ledger := filepath.Join(tmpDir, "audit.jsonl")
+		args := []string{`audit`, `--root`, root, `--ledger`, ledger, `--dry-run`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "dry run", stdout)
+			be.In(t, "coverage: 0.0%", stdout)
+		})
+		_, err := os.Stat(ledger)
+		be.True(t, os.IsNotExist(err))
+		args = []string{`audit`, `--root`, root, `--ledger`, ledger}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "1 unaudited", stdout)
+		})
+		// max-age
+		args = []string{`audit`, `--root`, root, `--ledger`, ledger, `--max-age`, `1ns`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "1 expired", stdout)
+		})
+		// sample
+		args = []string{`audit`, `--root`, root, `--ledger`, ledger, `--sample`, `100`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "1 sampled", stdout)
+		})
+		// changed object
+		args = []string{`commit`, `--root`, root, `--id`, id, `-m`, "update", `-n`, "Tester", contentFixture}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+		})
+		args = []string{`audit`, `--root`, root, `--ledger`, ledger}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "1 changed", stdout)
+		})
+	})
+}
@@ -0,0 +1,162 @@
+package run
+
+import (
+	"cmp"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/cache"
+)
+
+const cacheHelp = "commands for working with the local cache used for S3 and http storage roots"
+
+const (
+	envVarCache        = "OCFL_CACHE"          // "true" to use the cache
+	envVarCacheDir     = "OCFL_CACHE_DIR"      // cache directory
+	envVarCacheMaxSize = "OCFL_CACHE_MAX_SIZE" // maximum cache size (e.g., "10GiB")
+	envVarXDGCacheHome = "XDG_CACHE_HOME"      // used for default cache directory
+
+	defaultCacheMaxSize = 1 << 30 // 1 GiB
+)
+
+type CacheCmd struct {
+	Stats CacheStatsCmd `cmd:"" help:"Show the cache's location and size"`
+	Clear CacheClearCmd `cmd:"" help:"Remove all files from the cache"`
+}
+
+// cache stats
+type CacheStatsCmd struct{}
+
+func (cmd *CacheStatsCmd) Run(g *globals) error {
+	settings, err := g.cacheSettings(g.rootConfig())
+	if err != nil {
+		return err
+	}
+	c, err := settings.open()
+	if err != nil {
+		return err
+	}
+	stats, err := c.Stats()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(g.stdout, "directory:", c.Dir())
+	fmt.Fprintln(g.stdout, "enabled:  ", settings.enabled)
+	fmt.Fprintln(g.stdout, "files:    ", stats.Files)
+	fmt.Fprintf(g.stdout, "size:      %s of %s\n", formatBytes(stats.Size), formatBytes(c.MaxSize()))
+	return nil
+}
+
+// cache clear
+type CacheClearCmd struct{}
+
+func (cmd *CacheClearCmd) Run(g *globals) error {
+	settings, err := g.cacheSettings(g.rootConfig())
+	if err != nil {
+		return err
+	}
+	c, err := settings.open()
+	if err != nil {
+		return err
+	}
+	if err := c.Clear(); err != nil {
+		return err
+	}
+	g.logger.Info("cache cleared", "dir", c.Dir())
+	return nil
+}
+
+// cacheSettings are local cache settings from the environment and the config
+// file.
+type cacheSettings struct {
+	enabled bool
+	dir     string
+	maxSize int64
+}
+
+func (s cacheSettings) open() (*cache.Cache, error) {
+	if s.dir == "" {
+		return nil, fmt.Errorf("cache directory not set: use %s or the config file's [cache] dir setting", envVarCacheDir)
+	}
+	return cache.Open(s.dir, s.maxSize)
+}
+
+// cacheSettings returns the cache settings for the named root's config. The
+// named root's cache setting takes precedence over the environment, which
+// takes precedence over the config file's [cache] settings.
+func (g *globals) cacheSettings(rootConf *rootConfig) (cacheSettings, error) {
+	conf := g.config.Cache
+	settings := cacheSettings{
+		enabled: conf.Enabled,
+		dir:     cmp.Or(g.getenv(envVarCacheDir), conf.Dir),
+		maxSize: cmp.Or(int64(conf.MaxSize), defaultCacheMaxSize),
+	}
+	if val := g.getenv(envVarCache); val != "" {
+		enabled, err := strconv.ParseBool(val)
+		if err != nil {
+			return settings, fmt.Errorf("invalid %s value %q: must be true or false", envVarCache, val)
+		}
+		settings.enabled = enabled
+	}
+	if rootConf != nil && rootConf.Cache != nil {
+		settings.enabled = *rootConf.Cache
+	}
+	if val := g.getenv(envVarCacheMaxSize); val != "" {
+		size, err := parseByteSize(val)
+		if err != nil {
+			return settings, fmt.Errorf("invalid %s: %w", envVarCacheMaxSize, err)
+		}
+		settings.maxSize = size
+	}
+	if settings.dir == "" {
+		if dir := g.getenv(envVarXDGCacheHome); dir != "" {
+			settings.dir = filepath.Join(dir, "ocfl")
+		} else if home := g.getenv(envVarHome); home != "" {
+			settings.dir = filepath.Join(home, ".cache", "ocfl")
+		}
+	}
+	return settings, nil
+}
+
+// withCache wraps the remote FS with the local cache if it is enabled.
+// location identifies the FS in the cache.
+func (g *globals) withCache(fsys ocflfs.FS, location string, rootConf *rootConfig) (ocflfs.FS, error) {
+	if g.noCache {
+		return fsys, nil
+	}
+	settings, err := g.cacheSettings(rootConf)
+	if err != nil || !settings.enabled {
+		return fsys, err
+	}
+	c, err := settings.open()
+	if err != nil {
+		return nil, err
+	}
+	return c.Wrap(fsys, location), nil
+}
+
+// parseByteSize parses sizes like "1024", "500MB", or "10GiB". KB, MB, etc.
+// are powers of 1000; KiB, MiB, etc. are powers of 1024.
+func parseByteSize(val string) (int64, error) {
+	val = strings.TrimSpace(val)
+	num := strings.TrimRight(val, "KMGTPBikmgtpb ")
+	unit := strings.ToUpper(strings.TrimSpace(val[len(num):]))
+	size, err := strconv.ParseFloat(num, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid size %q", val)
+	}
+	mult := map[string]float64{
+		"": 1, "B": 1,
+		"K": 1e3, "KB": 1e3, "M": 1e6, "MB": 1e6, "G": 1e9, "GB": 1e9, "T": 1e12, "TB": 1e12,
+		"KIB": 1 << 10, "MIB": 1 << 20, "GIB": 1 << 30, "TIB": 1 << 40,
+	}
+	m, ok := mult[unit]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q", val)
+	}
+	return int64(size * m), nil
+}
@@ -0,0 +1,73 @@
+package run_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/testutil"
+)
+
+func TestCache(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		requested = map[string]int{} // GET requests by file name
+	)
+	fileSrv := http.FileServer(http.FS(testutil.TestDataFS()))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			mu.Lock()
+			requested[path.Base(r.URL.Path)]++
+			mu.Unlock()
+		}
+		fileSrv.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	objURL, err := url.JoinPath(srv.URL, "testdata", "object-fixtures", "1.1", "good-objects", "spec-ex-full")
+	be.NilErr(t, err)
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	env := map[string]string{"OCFL_CACHE": "true", "OCFL_CACHE_DIR": cacheDir}
+	export := func(t *testing.T) {
+		args := []string{"export", "--object", objURL, "--file", "image.tiff", "--to", "-"}
+		testutil.RunCLI(args, env, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.Equal(t, 2021, len(stdout))
+		})
+	}
+	t.Run("export", func(t *testing.T) {
+		export(t)
+		export(t)
+		be.Equal(t, 1, requested["inventory.json"])
+		be.Equal(t, 1, requested["image.tiff"])
+		be.Equal(t, 2, requested["inventory.json.sha512"])
+	})
+	t.Run("stats", func(t *testing.T) {
+		testutil.RunCLI([]string{"cache", "stats"}, env, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, cacheDir, stdout)
+			be.In(t, "files:     2", stdout)
+			be.In(t, "of 1.0 GiB", stdout)
+		})
+	})
+	t.Run("clear", func(t *testing.T) {
+		testutil.RunCLI([]string{"cache", "clear"}, env, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+		})
+		testutil.RunCLI([]string{"cache", "stats"}, env, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "files:     0", stdout)
+		})
+	})
+	t.Run("invalid max size", func(t *testing.T) {
+		env := map[string]string{"OCFL_CACHE": "true", "OCFL_CACHE_DIR": cacheDir, "OCFL_CACHE_MAX_SIZE": "lots"}
+		testutil.RunCLI([]string{"ls", "--object", objURL}, env, func(err error, stdout string, stderr string) {
+			be.True(t, err != nil)
+			be.In(t, "OCFL_CACHE_MAX_SIZE", stderr)
+		})
+	})
+}
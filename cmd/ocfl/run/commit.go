@@ -13,7 +13,7 @@ type CommitCmd struct {
 	Message  string `name:"message" short:"m" help:"Message to include in the object version metadata"`
 	Name     string `name:"name" short:"n" help:"Username to include in the object version metadata ($$${env_user_name})"`
 	Email    string `name:"email" short:"e" help:"User email to include in the object version metadata ($$${env_user_email})"`
-	Alg      string `name:"alg" default:"sha512" help:"Digest algorithm (ignored for commits to existing objects)"`
+	Alg      string `name:"alg" help:"Digest algorithm (ignored for commits to existing objects). Defaults to the storage root's digest_algorithm setting or sha512."`
 	NoHidden bool   `name:"no-hidden" help:"exclude hidden files and directories (.*)"`
 	Path     string `arg:"" name:"path" help:"local directory with object state to commit"`
 }
@@ -28,7 +28,7 @@ func (cmd *CommitCmd) Run(g *globals) error {
 	if err != nil {
 		return err
 	}
-	changes, err := stage.NewStageFile(obj, cmd.Alg)
+	changes, err := g.newStageFile(obj, cmd.Alg)
 	if err != nil {
 		return err
 	}
@@ -40,16 +40,10 @@ func (cmd *CommitCmd) Run(g *globals) error {
 	if err := changes.AddDir(ctx, cmd.Path, opts...); err != nil {
 		return err
 	}
-	if cmd.Name == "" {
-		cmd.Name = g.getenv(envVarUserName)
-	}
-	if cmd.Email == "" {
-		cmd.Email = g.getenv(envVarUserEmail)
-	}
 	stage, err := changes.Stage()
 	if err != nil {
 		return fmt.Errorf("stage has errors: %w", err)
 	}
-	_, err = objectUpdateOrRevert(ctx, obj, stage, cmd.Message, newUser(cmd.Name, cmd.Email), g.logger)
+	_, err = objectUpdateOrRevert(ctx, obj, stage, cmd.Message, g.user(cmd.Name, cmd.Email), g.logger)
 	return err
 }
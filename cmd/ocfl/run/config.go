@@ -0,0 +1,179 @@
+package run
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+const (
+	envVarConfig        = "OCFL_CONFIG"     // config file path
+	envVarXDGConfigHome = "XDG_CONFIG_HOME" // used for default config file path
+	envVarHome          = "HOME"
+
+	// prefix for storage root names used with --root
+	rootNamePrefix = "@"
+
+	defaultDigestAlg = "sha512"
+)
+
+// configFile is the contents of the config file. For example:
+//
+//	root = "@archive" # default storage root
+//
+//	[roots.archive]
+//	location = "s3://my-bucket/archive"
+//	endpoint = "https://s3.example.com"
+//	region = "us-west-2"
+//	profile = "archive"
+//	path_style = true
+//	user_name = "Archivist"
+//	user_email = "archivist@example.com"
+//	digest_algorithm = "sha512"
+//	fixity_algorithms = ["md5"]
+//
+//	[cache]
+//	enabled = true
+//	dir = "/var/cache/ocfl"
+//	max_size = "10GiB"
+//
+//	[roots.web]
+//	location = "https://example.com/ocfl"
+//	token = "s3cr3t"
+//	headers = { Cookie = "session=abc" }
+//	ca_file = "/etc/ssl/example-ca.pem"
+//	timeout = "30s"
+//	retries = 3
+//	cache = false # don't use the cache for this root
+type configFile struct {
+	// Root is the storage root location or name used if --root and $OCFL_ROOT
+	// aren't set.
+	Root string `toml:"root"`
+	// Roots are named storage roots
+	Roots map[string]*rootConfig `toml:"roots"`
+	// Cache configures the local cache for S3 and http storage roots.
+	Cache cacheConfig `toml:"cache"`
+}
+
+// cacheConfig is the configuration for the local cache.
+type cacheConfig struct {
+	Enabled bool     `toml:"enabled"`  // use the cache for S3 and http roots
+	Dir     string   `toml:"dir"`      // cache directory
+	MaxSize byteSize `toml:"max_size"` // maximum size of cached files
+}
+
+// rootConfig is the configuration for a named storage root.
+type rootConfig struct {
+	Location         string   `toml:"location"`          // storage root location
+	Endpoint         string   `toml:"endpoint"`          // s3 endpoint URL
+	Region           string   `toml:"region"`            // s3 region
+	Profile          string   `toml:"profile"`           // AWS shared config profile
+	PathStyle        *bool    `toml:"path_style"`        // s3 path-style requests (overrides $OCFL_S3_PATHSTYLE)
+	UserName         string   `toml:"user_name"`         // default user name for commits
+	UserEmail        string   `toml:"user_email"`        // default user email for commits
+	DigestAlgorithm  string   `toml:"digest_algorithm"`  // digest algorithm for new objects
+	FixityAlgorithms []string `toml:"fixity_algorithms"` // fixity algorithms for new content
+
+	// settings for http(s) locations
+	Headers  map[string]string `toml:"headers"`   // headers added to requests
+	Token    string            `toml:"token"`     // bearer token
+	Username string            `toml:"username"`  // basic auth username
+	Password string            `toml:"password"`  // basic auth password
+	CAFile   string            `toml:"ca_file"`   // PEM file with CA certificates
+	CertFile string            `toml:"cert_file"` // PEM file with client certificate
+	KeyFile  string            `toml:"key_file"`  // PEM file with client key
+	Timeout  duration          `toml:"timeout"`   // response timeout
+	Retries  *int              `toml:"retries"`   // retries for temporary errors
+
+	Cache *bool `toml:"cache"` // use the local cache (overrides [cache] enabled)
+}
+
+// duration is a time.Duration in the config file, like "30s".
+type duration struct {
+	time.Duration
+}
+
+func (d *duration) UnmarshalText(text []byte) error {
+	var err error
+	d.Duration, err = time.ParseDuration(string(text))
+	return err
+}
+
+// byteSize is a size in bytes in the config file, like "500MiB".
+type byteSize int64
+
+func (b *byteSize) UnmarshalText(text []byte) error {
+	size, err := parseByteSize(string(text))
+	*b = byteSize(size)
+	return err
+}
+
+// configPath returns the config file path from $OCFL_CONFIG or the default
+// location in the user's config directory. It returns an empty string if the
+// path can't be determined.
+func configPath(getenv func(string) string) string {
+	if p := getenv(envVarConfig); p != "" {
+		return p
+	}
+	if dir := getenv(envVarXDGConfigHome); dir != "" {
+		return filepath.Join(dir, "ocfl", "config.toml")
+	}
+	if home := getenv(envVarHome); home != "" {
+		return filepath.Join(home, ".config", "ocfl", "config.toml")
+	}
+	return ""
+}
+
+// readConfig reads the config file at name. If name is empty or the file
+// doesn't exist, an empty config is returned.
+func readConfig(name string) (*configFile, error) {
+	cfg := &configFile{}
+	if name == "" {
+		return cfg, nil
+	}
+	meta, err := toml.DecodeFile(name, cfg)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, k := range undecoded {
+			keys[i] = k.String()
+		}
+		return nil, fmt.Errorf("reading config file %s: unknown settings: %s", name, strings.Join(keys, ", "))
+	}
+	for name, root := range cfg.Roots {
+		if root.Location == "" {
+			return nil, fmt.Errorf("reading config file: root %q: location not set", name)
+		}
+		if strings.HasPrefix(root.Location, rootNamePrefix) {
+			return nil, fmt.Errorf("reading config file: root %q: location can't be another root's name", name)
+		}
+	}
+	return cfg, nil
+}
+
+// namedRoot returns the configuration for the storage root name (without the
+// "@" prefix).
+func (cfg *configFile) namedRoot(name string) (*rootConfig, error) {
+	root := cfg.Roots[name]
+	if root == nil {
+		names := slices.Sorted(maps.Keys(cfg.Roots))
+		if len(names) == 0 {
+			return nil, fmt.Errorf("storage root %q isn't defined: no roots in the config file", rootNamePrefix+name)
+		}
+		return nil, fmt.Errorf("storage root %q isn't defined in the config file (available: %s)",
+			rootNamePrefix+name, rootNamePrefix+strings.Join(names, ", "+rootNamePrefix))
+	}
+	return root, nil
+}
@@ -0,0 +1,136 @@
+package run_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/testutil"
+)
+
+func TestConfig(t *testing.T) {
+	tmp, fixtures := testutil.TempDirTestData(t,
+		`testdata/store-fixtures/1.0/good-stores/reg-extension-dir-root`,
+		`testdata/content-fixture`,
+	)
+	rootPath := fixtures[0]
+	contentPath := fixtures[1]
+	configFile := filepath.Join(tmp, "ocfl", "config.toml")
+	be.NilErr(t, os.MkdirAll(filepath.Dir(configFile), 0755))
+	be.NilErr(t, os.WriteFile(configFile, []byte(`
+root = "@test"
+
+[roots.test]
+location = "`+filepath.ToSlash(rootPath)+`"
+user_name = "Config User"
+user_email = "config@example.com"
+digest_algorithm = "sha256"
+fixity_algorithms = ["md5"]
+`), 0644))
+	env := map[string]string{"XDG_CONFIG_HOME": tmp}
+
+	t.Run("named root", func(t *testing.T) {
+		args := []string{"ls", "--root", "@test", "--id", "ark:123/abc"}
+		testutil.RunCLI(args, env, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "a_file.txt", stdout)
+		})
+	})
+	t.Run("default root", func(t *testing.T) {
+		testutil.RunCLI([]string{"ls"}, env, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "ark:123/abc", stdout)
+		})
+	})
+	t.Run("commit defaults", func(t *testing.T) {
+		args := []string{"commit", "--root", "@test", "--id", "new-object", "-m", "first", contentPath}
+		testutil.RunCLI(args, env, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+		})
+		args = []string{"log", "--root", "@test", "--id", "new-object"}
+		testutil.RunCLI(args, env, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "Config User", stdout)
+		})
+		args = []string{"info", "--root", "@test", "--id", "new-object"}
+		testutil.RunCLI(args, env, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "digest algorithm: sha256", stdout)
+		})
+		args = []string{"ls", "--root", "@test", "--id", "new-object", "--long", "--json"}
+		testutil.RunCLI(args, env, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, `"md5":`, stdout)
+		})
+	})
+	t.Run("flags and env take precedence", func(t *testing.T) {
+		args := []string{"ls", "--root", rootPath}
+		testutil.RunCLI(args, env, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "ark:123/abc", stdout)
+		})
+	})
+	t.Run("undefined root", func(t *testing.T) {
+		args := []string{"ls", "--root", "@missing"}
+		testutil.RunCLI(args, env, func(err error, stdout string, stderr string) {
+			be.True(t, err != nil)
+			be.In(t, `"@missing" isn't defined`, stderr)
+			be.In(t, "@test", stderr)
+		})
+	})
+	t.Run("http root settings", func(t *testing.T) {
+		fileSrv := http.FileServer(http.FS(testutil.TestDataFS()))
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, pass, _ := r.BasicAuth()
+			if user != "user" || pass != "pass" || r.Header.Get("X-Test") != "value" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			fileSrv.ServeHTTP(w, r)
+		}))
+		defer srv.Close()
+		httpConfig := filepath.Join(tmp, "http.toml")
+		be.NilErr(t, os.WriteFile(httpConfig, []byte(`
+[roots.web]
+location = "`+srv.URL+`/testdata/store-fixtures/1.0/good-stores/reg-extension-dir-root"
+username = "user"
+password = "pass"
+headers = { X-Test = "value" }
+timeout = "10s"
+retries = 0
+`), 0644))
+		args := []string{"ls", "--root", "@web", "--id", "ark:123/abc"}
+		testutil.RunCLI(args, map[string]string{"OCFL_CONFIG": httpConfig}, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "a_file.txt", stdout)
+		})
+	})
+	t.Run("cache settings", func(t *testing.T) {
+		cacheConfig := filepath.Join(tmp, "cache.toml")
+		cacheDir := filepath.Join(tmp, "cache")
+		be.NilErr(t, os.WriteFile(cacheConfig, []byte(`
+[cache]
+enabled = true
+dir = "`+filepath.ToSlash(cacheDir)+`"
+max_size = "10MiB"
+`), 0644))
+		testutil.RunCLI([]string{"cache", "stats"}, map[string]string{"OCFL_CONFIG": cacheConfig}, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, cacheDir, stdout)
+			be.In(t, "enabled:   true", stdout)
+			be.In(t, "of 10.0 MiB", stdout)
+		})
+	})
+	t.Run("unknown setting", func(t *testing.T) {
+		badConfig := filepath.Join(tmp, "bad.toml")
+		be.NilErr(t, os.WriteFile(badConfig, []byte("[roots.test]\nlocation = \"/tmp\"\nregoin = \"us-east-1\"\n"), 0644))
+		args := []string{"ls", "--root", "@test"}
+		testutil.RunCLI(args, map[string]string{"OCFL_CONFIG": badConfig}, func(err error, stdout string, stderr string) {
+			be.True(t, err != nil)
+			be.In(t, "roots.test.regoin", stderr)
+		})
+	})
+}
@@ -1,29 +1,138 @@
 package run
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
 
 	"github.com/srerickson/ocfl-go"
+	"github.com/srerickson/ocfl-go/digest"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
 	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/diff"
 )
 
-const diffHelp = "Show changed files between versions of an object"
+const diffHelp = "Show changed files between object versions and local directories"
 
 type DiffCmd struct {
-	ID string `name:"id" short:"i" optional:"" help:"The id for object to diff"`
-	Vs []int  `name:"versions" short:"v" default:"-1,0" help:"Object versions to compare, separated by commas. 0 refers to HEAD, negative numbers match versions before HEAD."`
+	ID      string `name:"id" short:"i" optional:"" help:"The id for object to diff"`
+	ObjPath string `name:"object" help:"full path to object root. If set, --root and --id are ignored."`
+	Vs      []int  `name:"versions" short:"v" default:"-1,0" help:"Object versions to compare, separated by commas. 0 refers to HEAD, negative numbers match versions before HEAD."`
+	Left    string `name:"left" help:"The basis for comparison: 'id:<id>[@<version>]', 'object:<path>[@<version>]', or 'dir:<path>'. Defaults to the head of the object set with --id or --object."`
+	Right   string `name:"right" help:"The target for comparison, using the same format as --left. Defaults to the head of the object set with --id or --object."`
+	Jobs    int    `name:"jobs" short:"j" default:"0" help:"number of files to digest concurrently in local directories. Defaults to the number of CPU cores."`
+	Stat    bool   `name:"stat" help:"show size changes for each file and totals"`
+	JSON    bool   `name:"json" help:"print changes as JSON"`
+	Patch   bool   `name:"patch" short:"p" help:"show unified diffs for modified text files"`
+	MaxSize int64  `name:"patch-max-size" default:"1048576" help:"maximum size (in bytes) of files to show unified diffs for"`
+	NoColor bool   `name:"no-color" help:"disable colors. Colors are only used if stdout is a terminal."`
+
+	FileRenames bool    `name:"file-renames" help:"only show renames of individual files with the same content, pairing files with duplicate content by sort order. Renamed directories and renamed and modified files aren't detected."`
+	Similarity  float64 `name:"similarity" default:"0.5" help:"minimum similarity (0-1) of the lines in a removed and an added text file with the same name for the file to be shown as renamed and modified. 0 disables similarity checks."`
+}
+
+// diffOutput is the JSON output of the diff command
+type diffOutput struct {
+	diff.Result
+	Stat    *diffStat         `json:"stat,omitempty"`
+	Patches map[string]string `json:"patches,omitempty"`
+}
+
+// diffStat are size changes for changed files
+type diffStat struct {
+	Files        []diffFileStat `json:"files"`
+	BytesAdded   int64          `json:"bytes_added"`
+	BytesRemoved int64          `json:"bytes_removed"`
+}
+
+type diffFileStat struct {
+	Path        string `json:"path"`
+	Change      string `json:"change"` // added, removed, modified, or renamed
+	RenamedFrom string `json:"renamed_from,omitempty"`
+	OldSize     int64  `json:"old_size"`
+	NewSize     int64  `json:"new_size"`
+}
+
+// diffTarget is one side of a diff: an object version or a local directory.
+type diffTarget struct {
+	kind    string // "id", "object", or "dir"
+	loc     string // object id, object path, or local directory
+	version int    // object version: 0 is HEAD, negative numbers are before HEAD
+	obj     *ocfl.Object
+	sizes   map[string]int64 // file sizes by logical path
 }
 
 func (cmd *DiffCmd) Run(g *globals) error {
-	root, err := g.getRoot()
-	if err != nil {
-		return err
+	if cmd.Left == "" && cmd.Right == "" {
+		return cmd.diffVersions(g)
+	}
+	var targets [2]*diffTarget
+	for i, spec := range []string{cmd.Left, cmd.Right} {
+		var err error
+		switch {
+		case spec != "":
+			targets[i], err = parseDiffTarget(spec)
+		case cmd.ObjPath != "":
+			targets[i] = &diffTarget{kind: "object", loc: cmd.ObjPath}
+		case cmd.ID != "":
+			targets[i] = &diffTarget{kind: "id", loc: cmd.ID}
+		default:
+			err = errors.New("both --left and --right are required if --id and --object aren't set")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// objects are opened first: their digest algorithm is used for local
+	// directories.
+	var alg digest.Algorithm
+	for _, target := range targets {
+		if target.kind == "dir" {
+			continue
+		}
+		if err := target.open(g); err != nil {
+			return err
+		}
+		objAlg := target.obj.DigestAlgorithm()
+		if alg != nil && alg.ID() != objAlg.ID() {
+			return fmt.Errorf("can't compare objects with different digest algorithms: %s and %s", alg.ID(), objAlg.ID())
+		}
+		alg = objAlg
+	}
+	if alg == nil {
+		alg = digest.SHA512
 	}
-	obj, err := root.NewObject(g.ctx, cmd.ID)
+	jobs := cmd.Jobs
+	if jobs < 1 {
+		jobs = runtime.NumCPU()
+	}
+	var states [2]ocfl.PathMap
+	for i, target := range targets {
+		var err error
+		states[i], err = target.state(g.ctx, alg, jobs)
+		if err != nil {
+			return err
+		}
+	}
+	return cmd.print(g, targets[0], targets[1], states[0], states[1])
+}
+
+// diffVersions compares two versions of the object set with --id or --object.
+func (cmd *DiffCmd) diffVersions(g *globals) error {
+	obj, err := g.newObject(cmd.ID, cmd.ObjPath)
 	if err != nil {
-		return fmt.Errorf("reading object id: %q: %w", cmd.ID, err)
+		return err
 	}
 	if !obj.Exists() {
 		err := fmt.Errorf("object %q not found at root path %s: %w", cmd.ID, obj.Path(), fs.ErrNotExist)
@@ -41,38 +150,391 @@ func (cmd *DiffCmd) Run(g *globals) error {
 		v1 = cmd.Vs[0]
 		v2 = cmd.Vs[1]
 	}
-	head := obj.Head().Num()
-	if v1 > head || (head+v1 < 1) {
-		return fmt.Errorf("version %d is out of range (HEAD=%d)", v1, head)
+	left := &diffTarget{kind: "object", obj: obj, version: v1}
+	right := &diffTarget{kind: "object", obj: obj, version: v2}
+	leftPaths, err := left.state(g.ctx, nil, 0)
+	if err != nil {
+		return err
+	}
+	rightPaths, err := right.state(g.ctx, nil, 0)
+	if err != nil {
+		return err
+	}
+	return cmd.print(g, left, right, leftPaths, rightPaths)
+}
+
+// print writes the changes between the left and right states using the
+// command's output options.
+func (cmd *DiffCmd) print(g *globals, left, right *diffTarget, leftPaths, rightPaths ocfl.PathMap) error {
+	ctx := g.ctx
+	var opts []diff.Option
+	if !cmd.FileRenames {
+		opts = append(opts, diff.WithDirRenames())
+		if cmd.Similarity > 0 {
+			opts = append(opts, diff.WithSimilarity(cmd.Similarity, func(aPath, bPath string) (float64, error) {
+				return cmd.similarity(ctx, left, right, leftPaths, rightPaths, aPath, bPath)
+			}))
+		}
 	}
-	if v2 > head || (head+v2 < 1) {
-		return fmt.Errorf("version %d is out of range (HEAD=%d)", v2, head)
+	result, err := diff.Diff(leftPaths, rightPaths, opts...)
+	if err != nil {
+		return err
 	}
-	if v1 < 0 {
-		v1 = head + v1
+	var stat *diffStat
+	if cmd.Stat || cmd.Patch {
+		if err := left.setSizes(ctx, leftPaths); err != nil {
+			return err
+		}
+		if err := right.setSizes(ctx, rightPaths); err != nil {
+			return err
+		}
+		stat = newDiffStat(result, left.sizes, right.sizes)
 	}
-	if v2 < 0 {
-		v2 = head + v2
+	var patches map[string]string
+	if cmd.Patch {
+		patches = map[string]string{}
+		for _, name := range result.Modified {
+			patch, err := cmd.patch(ctx, left, right, name, name)
+			if err != nil {
+				return err
+			}
+			patches[name] = patch
+		}
+		for src, dst := range result.RenamedModified {
+			patch, err := cmd.patch(ctx, left, right, src, dst)
+			if err != nil {
+				return err
+			}
+			patches[dst] = patch
+		}
 	}
-	var v1Paths, v2Paths ocfl.PathMap
-	if v := obj.Version(v1); v != nil && v.State() != nil {
-		v1Paths = v.State().PathMap()
+	if cmd.JSON {
+		out := diffOutput{Result: result, Patches: patches}
+		if cmd.Stat {
+			out.Stat = stat
+		}
+		// use empty values rather than null
+		if out.Added == nil {
+			out.Added = []string{}
+		}
+		if out.Removed == nil {
+			out.Removed = []string{}
+		}
+		if out.Modified == nil {
+			out.Modified = []string{}
+		}
+		if out.Renamed == nil {
+			out.Renamed = map[string]string{}
+		}
+		if out.RenamedModified == nil {
+			out.RenamedModified = map[string]string{}
+		}
+		return printJSON(g.stdout, out)
 	}
-	if v := obj.Version(v2); v != nil && v.State() != nil {
-		v2Paths = v.State().PathMap()
+	printer := diff.NewPrinter(g.stdout, !cmd.NoColor)
+	if cmd.Stat {
+		stat.print(g.stdout)
+	} else {
+		printer.Result(result)
 	}
-	if v1Paths == nil {
-		return fmt.Errorf("version not found: %d", v1)
+	for _, name := range slices.Sorted(maps.Keys(patches)) {
+		if patches[name] != "" {
+			printer.Patch(patches[name])
+		}
 	}
-	if v2Paths == nil {
-		return fmt.Errorf("version not found: %d", v2)
+	return nil
+}
+
+// patch returns a unified diff for the file aPath in left and bPath in right,
+// or a message if either file is binary or too large.
+func (cmd *DiffCmd) patch(ctx context.Context, left, right *diffTarget, aPath, bPath string) (string, error) {
+	aName, bName := "a/"+aPath, "b/"+bPath
+	if left.sizes[aPath] > cmd.MaxSize || right.sizes[bPath] > cmd.MaxSize {
+		return fmt.Sprintf("Files %s and %s differ (larger than %s)\n", aName, bName, formatBytes(cmd.MaxSize)), nil
 	}
-	result, err := diff.Diff(v1Paths, v2Paths)
+	a, err := left.readFile(ctx, aPath)
 	if err != nil {
-		return err
+		return "", err
+	}
+	b, err := right.readFile(ctx, bPath)
+	if err != nil {
+		return "", err
+	}
+	if isBinary(a) || isBinary(b) {
+		return fmt.Sprintf("Binary files %s and %s differ\n", aName, bName), nil
+	}
+	return diff.Unified(aName, bName, a, b, 3), nil
+}
+
+// similarity returns the similarity of the text files aPath in left and bPath
+// in right. Binary files and files larger than the patch size limit have no
+// similarity.
+func (cmd *DiffCmd) similarity(ctx context.Context, left, right *diffTarget, leftPaths, rightPaths ocfl.PathMap, aPath, bPath string) (float64, error) {
+	if err := left.setSizes(ctx, leftPaths); err != nil {
+		return 0, err
+	}
+	if err := right.setSizes(ctx, rightPaths); err != nil {
+		return 0, err
+	}
+	if left.sizes[aPath] > cmd.MaxSize || right.sizes[bPath] > cmd.MaxSize {
+		return 0, nil
+	}
+	a, err := left.readFile(ctx, aPath)
+	if err != nil {
+		return 0, err
+	}
+	b, err := right.readFile(ctx, bPath)
+	if err != nil {
+		return 0, err
+	}
+	if isBinary(a) || isBinary(b) {
+		return 0, nil
+	}
+	return diff.Similarity(a, b), nil
+}
+
+// parseDiffTarget parses a --left or --right value.
+func parseDiffTarget(spec string) (*diffTarget, error) {
+	kind, loc, found := strings.Cut(spec, ":")
+	if !found || loc == "" {
+		return nil, fmt.Errorf("invalid diff target %q: expected 'id:<id>', 'object:<path>', or 'dir:<path>'", spec)
+	}
+	target := &diffTarget{kind: kind, loc: loc}
+	switch kind {
+	case "dir":
+		return target, nil
+	case "id", "object":
+	default:
+		return nil, fmt.Errorf("invalid diff target %q: unknown type %q", spec, kind)
+	}
+	// The version suffix is optional and ids may include '@', so the suffix is
+	// only used if it is a valid version.
+	if i := strings.LastIndex(loc, "@"); i > 0 {
+		if v, err := parseDiffVersion(loc[i+1:]); err == nil {
+			target.loc = loc[:i]
+			target.version = v
+		}
+	}
+	return target, nil
+}
+
+// parseDiffVersion parses a version as 'head', 'vN', or 'N'.
+func parseDiffVersion(val string) (int, error) {
+	if strings.EqualFold(val, "head") {
+		return 0, nil
+	}
+	var vnum ocfl.VNum
+	if err := ocfl.ParseVNum(val, &vnum); err == nil {
+		return vnum.Num(), nil
+	}
+	return strconv.Atoi(val)
+}
+
+// open reads the target's object.
+func (target *diffTarget) open(g *globals) error {
+	var err error
+	switch target.kind {
+	case "id":
+		target.obj, err = g.newObject(target.loc, "", ocfl.ObjectMustExist())
+	default:
+		target.obj, err = g.newObject("", target.loc, ocfl.ObjectMustExist())
+	}
+	return err
+}
+
+// state returns the logical state of the target. Files in local directories
+// are digested with alg, using jobs goroutines.
+func (target *diffTarget) state(ctx context.Context, alg digest.Algorithm, jobs int) (ocfl.PathMap, error) {
+	if target.kind != "dir" {
+		v, err := resolveVersion(target.obj, target.version)
+		if err != nil {
+			return nil, err
+		}
+		target.version = v
+		return versionState(target.obj, v)
+	}
+	dir, err := filepath.Abs(target.loc)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
 	}
-	if !result.Empty() {
-		fmt.Fprint(g.stdout, result.String())
+	if !info.IsDir() {
+		return nil, fmt.Errorf("not a directory: %s", target.loc)
+	}
+	state := ocfl.PathMap{}
+	target.sizes = map[string]int64{}
+	filesIter, walkErr := ocflfs.UntilErr(ocflfs.WalkFiles(ctx, ocflfs.DirFS(dir), "."))
+	for result, err := range digest.DigestFilesBatch(ctx, filesIter, jobs, alg) {
+		if err != nil {
+			return nil, err
+		}
+		state[result.FullPath()] = result.Digests[alg.ID()]
+		target.sizes[result.FullPath()] = result.Info.Size()
+	}
+	if err := walkErr(); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// setSizes sets the sizes of files in the target's state. Sizes for local
+// directories are set by state().
+func (target *diffTarget) setSizes(ctx context.Context, state ocfl.PathMap) error {
+	if target.sizes != nil {
+		return nil
+	}
+	fileSizes, err := contentFileSizes(ctx, target.obj)
+	if err != nil {
+		return fmt.Errorf("reading content sizes for object %q: %w", target.obj.ID(), err)
+	}
+	digestSizes := map[string]int64{}
+	for contentPath, dig := range target.obj.Manifest().Paths() {
+		digestSizes[dig] = fileSizes[contentPath]
+	}
+	target.sizes = map[string]int64{}
+	for name, dig := range state {
+		target.sizes[name] = digestSizes[dig]
 	}
 	return nil
 }
+
+// readFile returns the contents of the file with the logical path name.
+func (target *diffTarget) readFile(ctx context.Context, name string) ([]byte, error) {
+	if target.kind == "dir" {
+		return os.ReadFile(filepath.Join(target.loc, filepath.FromSlash(name)))
+	}
+	versionFS, err := target.obj.VersionFS(ctx, target.version)
+	if err != nil {
+		return nil, err
+	}
+	return fs.ReadFile(versionFS, name)
+}
+
+func newDiffStat(result diff.Result, leftSizes, rightSizes map[string]int64) *diffStat {
+	stat := &diffStat{Files: []diffFileStat{}}
+	for _, name := range result.Added {
+		stat.Files = append(stat.Files, diffFileStat{Path: name, Change: "added", NewSize: rightSizes[name]})
+		stat.BytesAdded += rightSizes[name]
+	}
+	for _, name := range result.Removed {
+		stat.Files = append(stat.Files, diffFileStat{Path: name, Change: "removed", OldSize: leftSizes[name]})
+		stat.BytesRemoved += leftSizes[name]
+	}
+	for _, name := range result.Modified {
+		file := diffFileStat{Path: name, Change: "modified", OldSize: leftSizes[name], NewSize: rightSizes[name]}
+		if delta := file.NewSize - file.OldSize; delta > 0 {
+			stat.BytesAdded += delta
+		} else {
+			stat.BytesRemoved -= delta
+		}
+		stat.Files = append(stat.Files, file)
+	}
+	for _, src := range slices.Sorted(maps.Keys(result.Renamed)) {
+		dst := result.Renamed[src]
+		stat.Files = append(stat.Files, diffFileStat{
+			Path:        dst,
+			Change:      "renamed",
+			RenamedFrom: src,
+			OldSize:     pathSize(leftSizes, src),
+			NewSize:     pathSize(rightSizes, dst),
+		})
+	}
+	for _, src := range slices.Sorted(maps.Keys(result.RenamedModified)) {
+		file := diffFileStat{
+			Path:        result.RenamedModified[src],
+			Change:      "modified",
+			RenamedFrom: src,
+			OldSize:     leftSizes[src],
+		}
+		file.NewSize = rightSizes[file.Path]
+		if delta := file.NewSize - file.OldSize; delta > 0 {
+			stat.BytesAdded += delta
+		} else {
+			stat.BytesRemoved -= delta
+		}
+		stat.Files = append(stat.Files, file)
+	}
+	return stat
+}
+
+// pathSize returns the size of the file name, or the total size of files in
+// the directory if name ends with '/'.
+func pathSize(sizes map[string]int64, name string) int64 {
+	if !strings.HasSuffix(name, "/") {
+		return sizes[name]
+	}
+	var total int64
+	for file, size := range sizes {
+		if strings.HasPrefix(file, name) {
+			total += size
+		}
+	}
+	return total
+}
+
+func (stat *diffStat) print(w io.Writer) {
+	counts := map[string]int{}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, file := range stat.Files {
+		counts[file.Change]++
+		switch file.Change {
+		case "added":
+			fmt.Fprintf(tw, "add:\t%s\t+%s\n", file.Path, formatBytes(file.NewSize))
+		case "removed":
+			fmt.Fprintf(tw, "rem:\t%s\t-%s\n", file.Path, formatBytes(file.OldSize))
+		case "modified":
+			delta := file.NewSize - file.OldSize
+			sign := "+"
+			if delta < 0 {
+				sign, delta = "-", -delta
+			}
+			name := file.Path
+			if file.RenamedFrom != "" {
+				name = "{ " + file.RenamedFrom + " => " + file.Path + " }"
+			}
+			fmt.Fprintf(tw, "mod:\t%s\t%s -> %s (%s%s)\n", name,
+				formatBytes(file.OldSize), formatBytes(file.NewSize), sign, formatBytes(delta))
+		case "renamed":
+			fmt.Fprintf(tw, "mov:\t{ %s => %s }\t%s\n", file.RenamedFrom, file.Path, formatBytes(file.NewSize))
+		}
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "%d added, %d modified, %d removed, %d renamed (+%s, -%s)\n",
+		counts["added"], counts["modified"], counts["removed"], counts["renamed"],
+		formatBytes(stat.BytesAdded), formatBytes(stat.BytesRemoved))
+}
+
+// isBinary returns true if data includes a NUL byte or isn't valid UTF-8.
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data)
+}
+
+// resolveVersion returns the version number for v, where 0 is HEAD and
+// negative numbers are versions before HEAD.
+func resolveVersion(obj *ocfl.Object, v int) (int, error) {
+	head := obj.Head().Num()
+	if v > head || (head+v < 1) {
+		return 0, fmt.Errorf("version %d is out of range (HEAD=%d)", v, head)
+	}
+	if v < 1 {
+		v = head + v
+	}
+	return v, nil
+}
+
+// versionState returns the logical state of the object version v, where 0 is
+// HEAD and negative numbers are versions before HEAD.
+func versionState(obj *ocfl.Object, v int) (ocfl.PathMap, error) {
+	v, err := resolveVersion(obj, v)
+	if err != nil {
+		return nil, err
+	}
+	ver := obj.Version(v)
+	if ver == nil || ver.State() == nil {
+		return nil, fmt.Errorf("version not found: %d", v)
+	}
+	return ver.State().PathMap(), nil
+}
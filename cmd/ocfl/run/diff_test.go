@@ -0,0 +1,229 @@
+package run_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/testutil"
+)
+
+func TestDiff(t *testing.T) {
+	_, fixtures := testutil.TempDirTestData(t,
+		`testdata/object-fixtures/1.1/good-objects/spec-ex-full`,
+		`testdata/store-fixtures/1.0/good-stores/reg-extension-dir-root`,
+		`testdata/content-fixture`,
+	)
+	obj := fixtures[0]
+	root := fixtures[1]
+	contentFixture := fixtures[2]
+
+	t.Run("versions", func(t *testing.T) {
+		args := []string{`diff`, `--object`, obj, `-v`, `1,3`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "mod: foo/bar.xml", stdout)
+			be.In(t, "mov: { empty.txt => empty2.txt }", stdout)
+		})
+	})
+
+	t.Run("object versions", func(t *testing.T) {
+		args := []string{`diff`, `--left`, `object:` + obj + `@v2`, `--right`, `object:` + obj + `@head`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.Equal(t, "add: image.tiff\nrem: empty.txt\n", stdout)
+		})
+	})
+
+	t.Run("object and directory", func(t *testing.T) {
+		dir := t.TempDir()
+		be.NilErr(t, os.WriteFile(filepath.Join(dir, "a_file.txt"), []byte("changed"), 0644))
+		be.NilErr(t, os.WriteFile(filepath.Join(dir, "new.txt"), []byte("new"), 0644))
+		args := []string{`diff`, `--root`, root, `--left`, `id:ark:123/abc@v1`, `--right`, `dir:` + dir, `--jobs`, `2`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "mod: a_file.txt", stdout)
+			be.In(t, "add: new.txt", stdout)
+		})
+		// --right defaults to the object's head
+		args = []string{`diff`, `--root`, root, `--id`, `ark:123/abc`, `--left`, `dir:` + dir}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "mod: a_file.txt", stdout)
+			be.In(t, "rem: new.txt", stdout)
+		})
+	})
+
+	t.Run("directories", func(t *testing.T) {
+		args := []string{`diff`, `--left`, `dir:` + contentFixture, `--right`, `dir:` + contentFixture}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.Equal(t, "", stdout)
+		})
+	})
+
+	t.Run("different objects", func(t *testing.T) {
+		args := []string{`diff`, `--root`, root, `--left`, `id:ark:123/abc`, `--right`, `object:` + obj}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "rem: a_file.txt", stdout)
+			be.In(t, "add: foo/bar.xml", stdout)
+		})
+	})
+
+	t.Run("invalid target", func(t *testing.T) {
+		args := []string{`diff`, `--left`, `file:` + obj, `--right`, `dir:` + contentFixture}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.Nonzero(t, err)
+			be.True(t, strings.Contains(err.Error(), "unknown type"))
+		})
+		args = []string{`diff`, `--left`, `dir:` + contentFixture}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.Nonzero(t, err)
+		})
+	})
+
+	t.Run("stat", func(t *testing.T) {
+		args := []string{`diff`, `--object`, obj, `-v`, `1,2`, `--stat`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "rem:  image.tiff", stdout)
+			be.In(t, "-2.0 KiB", stdout)
+			be.In(t, "272 B -> 272 B (+0 B)", stdout)
+			be.In(t, "1 added, 1 modified, 1 removed, 0 renamed (+0 B, -2.0 KiB)", stdout)
+		})
+	})
+
+	t.Run("stat over http", func(t *testing.T) {
+		srv := httptest.NewServer(http.FileServer(http.FS(testutil.TestDataFS())))
+		defer srv.Close()
+		objURL, err := url.JoinPath(srv.URL, "testdata", "object-fixtures", "1.1", "good-objects", "spec-ex-full")
+		be.NilErr(t, err)
+		args := []string{`diff`, `--object`, objURL, `-v`, `1,2`, `--stat`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "272 B -> 272 B (+0 B)", stdout)
+			be.In(t, "1 added, 1 modified, 1 removed, 0 renamed (+0 B, -2.0 KiB)", stdout)
+		})
+	})
+
+	t.Run("json", func(t *testing.T) {
+		args := []string{`diff`, `--object`, obj, `-v`, `1,3`, `--json`, `--stat`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			var out struct {
+				Added    []string          `json:"added"`
+				Modified []string          `json:"modified"`
+				Renamed  map[string]string `json:"renamed"`
+				Stat     struct {
+					Files []struct {
+						Path        string `json:"path"`
+						Change      string `json:"change"`
+						RenamedFrom string `json:"renamed_from"`
+					} `json:"files"`
+				} `json:"stat"`
+			}
+			be.NilErr(t, json.Unmarshal([]byte(stdout), &out))
+			be.Equal(t, 0, len(out.Added))
+			be.AllEqual(t, []string{"foo/bar.xml"}, out.Modified)
+			be.Equal(t, "empty2.txt", out.Renamed["empty.txt"])
+			be.Equal(t, 2, len(out.Stat.Files))
+			be.Equal(t, "renamed", out.Stat.Files[1].Change)
+			be.Equal(t, "empty.txt", out.Stat.Files[1].RenamedFrom)
+		})
+	})
+
+	t.Run("patch", func(t *testing.T) {
+		left, right := t.TempDir(), t.TempDir()
+		files := map[string][2]string{
+			"text.txt":   {"one\ntwo\nthree\n", "one\n2\nthree\n"},
+			"binary.dat": {"\x00\x01", "\x00\x02"},
+			"large.txt":  {strings.Repeat("a\n", 100), strings.Repeat("b\n", 100)},
+		}
+		for name, contents := range files {
+			be.NilErr(t, os.WriteFile(filepath.Join(left, name), []byte(contents[0]), 0644))
+			be.NilErr(t, os.WriteFile(filepath.Join(right, name), []byte(contents[1]), 0644))
+		}
+		args := []string{`diff`, `--left`, `dir:` + left, `--right`, `dir:` + right, `--patch`, `--patch-max-size`, `100`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "mod: text.txt", stdout)
+			be.In(t, "--- a/text.txt\n+++ b/text.txt\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n", stdout)
+			be.In(t, "Binary files a/binary.dat and b/binary.dat differ", stdout)
+			be.In(t, "Files a/large.txt and b/large.txt differ (larger than 100 B)", stdout)
+			be.False(t, strings.Contains(stdout, "\x1b["))
+		})
+		args = append(args, `--no-color`, `--json`)
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			var out struct {
+				Patches map[string]string `json:"patches"`
+			}
+			be.NilErr(t, json.Unmarshal([]byte(stdout), &out))
+			be.Equal(t, 3, len(out.Patches))
+			be.In(t, "+2\n", out.Patches["text.txt"])
+		})
+	})
+
+	t.Run("renamed and modified over http", func(t *testing.T) {
+		tmpRoot := filepath.Join(t.TempDir(), "root")
+		args := []string{`init-root`, `--root`, tmpRoot, `--layout`, `0002-flat-direct-storage-layout`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+		})
+		for i, name := range []string{"old/notes.txt", "new/notes.txt"} {
+			content := t.TempDir()
+			writeTestFile(t, filepath.Join(content, "keep.txt"), "unchanged\n")
+			writeTestFile(t, filepath.Join(content, name), "line 1\nline 2\nline 3\n"+strings.Repeat("line 4\n", i))
+			args := []string{`commit`, `--root`, tmpRoot, `--id`, `obj`, `-m`, name, `-n`, `Tester`, content}
+			testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+				be.NilErr(t, err)
+			})
+		}
+		srv := httptest.NewServer(http.FileServer(http.Dir(tmpRoot)))
+		defer srv.Close()
+		args = []string{`diff`, `--object`, srv.URL + `/obj`, `-v`, `1,2`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "old/notes.txt => new/notes.txt } (modified)", stdout)
+		})
+	})
+
+	t.Run("renamed directories", func(t *testing.T) {
+		left, right := t.TempDir(), t.TempDir()
+		for i := range 20 {
+			name := fmt.Sprintf("file-%d.txt", i)
+			content := fmt.Sprintf("line 1\nline 2\nfile %d\n", i)
+			writeTestFile(t, filepath.Join(left, "old", name), content)
+			if i == 0 {
+				content += "line 4\n"
+			}
+			writeTestFile(t, filepath.Join(right, "new", name), content)
+		}
+		args := []string{`diff`, `--left`, `dir:` + left, `--right`, `dir:` + right}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.Equal(t, "mov: { old/ => new/ }\nmov: { old/file-0.txt => new/file-0.txt } (modified)\n", stdout)
+		})
+		args = append(args, `--file-renames`)
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "add: new/file-0.txt", stdout)
+			be.In(t, "rem: old/file-0.txt", stdout)
+			be.Equal(t, 21, len(strings.Split(strings.TrimSpace(stdout), "\n")))
+		})
+	})
+}
+
+func writeTestFile(t *testing.T, name string, content string) {
+	t.Helper()
+	be.NilErr(t, os.MkdirAll(filepath.Dir(name), 0755))
+	be.NilErr(t, os.WriteFile(name, []byte(content), 0644))
+}
@@ -0,0 +1,164 @@
+package run
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"path"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/srerickson/ocfl-go"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"golang.org/x/sync/errgroup"
+)
+
+const gcHelp = "List (and optionally delete) files in object directories that aren't referenced by the object's inventory"
+
+type GCCmd struct {
+	ID     string `name:"id" short:"i" optional:"" help:"The ID of the object to check. If not set, all objects in the storage root are checked."`
+	Delete bool   `name:"delete" help:"delete unreferenced files"`
+	Jobs   int    `name:"jobs" short:"j" default:"0" help:"number of objects to check concurrently. Defaults to the number of CPU cores."`
+}
+
+// unreferencedFile is a file in an object directory that isn't part of the
+// object.
+type unreferencedFile struct {
+	objectID string
+	name     string // path relative to the object root
+	size     int64
+}
+
+func (cmd *GCCmd) Run(g *globals) error {
+	ctx := g.ctx
+	jobs := cmd.Jobs
+	if jobs < 1 {
+		jobs = runtime.NumCPU()
+	}
+	root, err := g.getRoot()
+	if err != nil {
+		return err
+	}
+	var objects []*ocfl.Object
+	if cmd.ID != "" {
+		obj, err := root.NewObject(ctx, cmd.ID, ocfl.ObjectMustExist())
+		if err != nil {
+			return fmt.Errorf("reading object id: %q: %w", cmd.ID, err)
+		}
+		objects = append(objects, obj)
+	} else {
+		for obj, err := range root.ObjectsBatch(ctx, jobs) {
+			if err != nil {
+				return fmt.Errorf("while listing objects in the storage root: %w", err)
+			}
+			objects = append(objects, obj)
+		}
+	}
+	results := make([][]unreferencedFile, len(objects))
+	grp, grpCtx := errgroup.WithContext(ctx)
+	grp.SetLimit(jobs)
+	for i, obj := range objects {
+		grp.Go(func() error {
+			files, err := unreferencedFiles(grpCtx, obj)
+			if err != nil {
+				return fmt.Errorf("checking object %q: %w", obj.ID(), err)
+			}
+			results[i] = files
+			return nil
+		})
+	}
+	if err := grp.Wait(); err != nil {
+		return err
+	}
+	var (
+		numFiles   int
+		totalSize  int64
+		numObjects int
+	)
+	for i, files := range results {
+		if len(files) == 0 {
+			continue
+		}
+		numObjects++
+		for _, f := range files {
+			numFiles++
+			totalSize += f.size
+			fmt.Fprintf(g.stdout, "%s\t%s\t%d\n", f.objectID, f.name, f.size)
+		}
+		if cmd.Delete {
+			if err := deleteObjectFiles(ctx, objects[i], files); err != nil {
+				return err
+			}
+		}
+	}
+	action := "found"
+	if cmd.Delete {
+		action = "deleted"
+	}
+	fmt.Fprintf(g.stdout, "%s %d unreferenced file(s) (%s) in %d of %d object(s)\n",
+		action, numFiles, formatBytes(totalSize), numObjects, len(objects))
+	return nil
+}
+
+// unreferencedFiles returns the files in the object's directory that aren't
+// in its manifest and aren't the object's declaration, inventory, or sidecar
+// files. Files in the object's extensions and logs directories are ignored.
+func unreferencedFiles(ctx context.Context, obj *ocfl.Object) ([]unreferencedFile, error) {
+	sizes, err := objectFileSizes(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	referenced := map[string]bool{}
+	for name := range obj.Manifest().Paths() {
+		referenced[name] = true
+	}
+	var versionDirs []string
+	for _, vnum := range obj.Head().Lineage() {
+		versionDirs = append(versionDirs, vnum.String())
+	}
+	var files []unreferencedFile
+	for name, size := range sizes {
+		if referenced[name] {
+			continue
+		}
+		dir, base := path.Split(name)
+		dir = strings.TrimSuffix(dir, "/")
+		top, _, _ := strings.Cut(name, "/")
+		switch {
+		case dir == "" && strings.HasPrefix(base, "0="):
+		case (dir == "" || slices.Contains(versionDirs, dir)) &&
+			(base == "inventory.json" || strings.HasPrefix(base, "inventory.json.")):
+		case dir != "" && (top == "extensions" || top == "logs"):
+		default:
+			files = append(files, unreferencedFile{objectID: obj.ID(), name: name, size: size})
+		}
+	}
+	slices.SortFunc(files, func(a, b unreferencedFile) int { return cmp.Compare(a.name, b.name) })
+	return files, nil
+}
+
+// deleteObjectFiles deletes files from the object's directory along with any
+// directories left empty.
+func deleteObjectFiles(ctx context.Context, obj *ocfl.Object, files []unreferencedFile) error {
+	dirs := map[string]bool{}
+	for _, f := range files {
+		if err := ocflfs.Remove(ctx, obj.FS(), path.Join(obj.Path(), f.name)); err != nil {
+			return fmt.Errorf("deleting %q from object %q: %w", f.name, obj.ID(), err)
+		}
+		for dir := path.Dir(f.name); dir != "."; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+	// remove parent directories, deepest first. Errors are ignored because
+	// the directories may not be empty (or may not exist, for backends
+	// without directories).
+	sorted := slices.SortedFunc(maps.Keys(dirs), func(a, b string) int {
+		return cmp.Compare(strings.Count(b, "/"), strings.Count(a, "/"))
+	})
+	for _, dir := range sorted {
+		ocflfs.Remove(ctx, obj.FS(), path.Join(obj.Path(), dir))
+	}
+	return nil
+}
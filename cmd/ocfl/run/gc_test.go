@@ -0,0 +1,61 @@
+package run_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/testutil"
+)
+
+func TestGC(t *testing.T) {
+	_, fixtures := testutil.TempDirTestData(t,
+		`testdata/store-fixtures/1.0/good-stores/reg-extension-dir-root`,
+	)
+	root := fixtures[0]
+	id := "ark:123/abc"
+	objDir := filepath.Join(root, "a47", "817", "83d", "cec", "ark%3a123%2fabc")
+
+	t.Run("no unreferenced files", func(t *testing.T) {
+		args := []string{`gc`, `--root`, root}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "found 0 unreferenced file(s) (0 B) in 0 of 1 object(s)", stdout)
+		})
+	})
+
+	// debris from an interrupted update
+	stray := filepath.Join(objDir, "v1", "content", "partial.tmp")
+	be.NilErr(t, os.WriteFile(stray, []byte("12345"), 0644))
+	be.NilErr(t, os.MkdirAll(filepath.Join(objDir, "v2", "content"), 0755))
+	be.NilErr(t, os.WriteFile(filepath.Join(objDir, "v2", "content", "new.txt"), []byte("abc"), 0644))
+
+	t.Run("list", func(t *testing.T) {
+		args := []string{`gc`, `--root`, root, `--id`, id}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, id+"\tv1/content/partial.tmp\t5\n", stdout)
+			be.In(t, id+"\tv2/content/new.txt\t3\n", stdout)
+			be.In(t, "found 2 unreferenced file(s)", stdout)
+		})
+		_, err := os.Stat(stray)
+		be.NilErr(t, err)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		args := []string{`gc`, `--root`, root, `--delete`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "deleted 2 unreferenced file(s)", stdout)
+		})
+		_, err := os.Stat(stray)
+		be.True(t, os.IsNotExist(err))
+		_, err = os.Stat(filepath.Join(objDir, "v2"))
+		be.True(t, os.IsNotExist(err))
+		args = []string{`validate`, `--root`, root}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+		})
+	})
+}
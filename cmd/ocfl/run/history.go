@@ -0,0 +1,193 @@
+package run
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/srerickson/ocfl-go"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/diff"
+)
+
+const historyHelp = "Show the history of a file in an object, following renames"
+
+type HistoryCmd struct {
+	ID      string `name:"id" short:"i" help:"The id for object with the file"`
+	ObjPath string `name:"object" help:"full path to object root. If set, --root and --id are ignored."`
+	Path    string `arg:"" name:"path" help:"logical path of the file in the object"`
+	JSON    bool   `name:"json" help:"print the history as JSON Lines"`
+	Export  string `name:"export" help:"export each revision of the file's content to the directory. Files are written to <dir>/<version>/<path>."`
+	Replace bool   `name:"replace" help:"replace existing files when exporting"`
+}
+
+// historyStatus describes how a file changed in a version
+type historyStatus string
+
+const (
+	historyAdded     historyStatus = "added"
+	historyModified  historyStatus = "modified"
+	historyRenamed   historyStatus = "renamed"
+	historyUnchanged historyStatus = "unchanged"
+	historyRemoved   historyStatus = "removed"
+)
+
+// historyEntry is the state of a file in a version
+type historyEntry struct {
+	Version     string        `json:"version"`
+	Created     time.Time     `json:"created"`
+	User        *ocfl.User    `json:"user,omitempty"`
+	Message     string        `json:"message"`
+	Status      historyStatus `json:"status"`
+	Path        string        `json:"path"`
+	RenamedFrom string        `json:"renamed_from,omitempty"`
+	Digest      string        `json:"digest,omitempty"`
+}
+
+func (cmd *HistoryCmd) Run(g *globals) error {
+	obj, err := g.newObject(cmd.ID, cmd.ObjPath, ocfl.ObjectMustExist())
+	if err != nil {
+		return err
+	}
+	history, err := fileHistory(obj, cmd.Path)
+	if err != nil {
+		return err
+	}
+	if cmd.Export != "" {
+		if err := cmd.export(g, obj, history); err != nil {
+			return err
+		}
+	}
+	if cmd.JSON {
+		enc := json.NewEncoder(g.stdout)
+		for _, entry := range history {
+			if err := enc.Encode(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	tw := tabwriter.NewWriter(g.stdout, 0, 4, 2, ' ', 0)
+	for _, entry := range history {
+		status := string(entry.Status)
+		if entry.Status == historyRenamed {
+			status += " from " + entry.RenamedFrom
+		}
+		user := ""
+		if entry.User != nil {
+			user = entry.User.Name
+		}
+		digest := entry.Digest
+		if len(digest) > 12 {
+			digest = digest[:12]
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%q\n", entry.Version, entry.Created.Format(time.DateOnly),
+			user, cmp.Or(digest, "-"), entry.Path, status, entry.Message)
+	}
+	return tw.Flush()
+}
+
+// export writes each revision of the file's content to the export directory.
+func (cmd *HistoryCmd) export(g *globals, obj *ocfl.Object, history []historyEntry) error {
+	for _, entry := range history {
+		if entry.Status != historyAdded && entry.Status != historyModified {
+			continue
+		}
+		var vnum ocfl.VNum
+		if err := ocfl.ParseVNum(entry.Version, &vnum); err != nil {
+			return err
+		}
+		versionFS, err := obj.VersionFS(g.ctx, vnum.Num())
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(cmd.Export, entry.Version, filepath.FromSlash(entry.Path))
+		if err := exportFile(versionFS, entry.Path, cmd.Replace, nil, dst); err != nil {
+			return fmt.Errorf("exporting %s from %s: %w", entry.Path, entry.Version, err)
+		}
+		g.logger.Info("exported", "version", entry.Version, "file", dst)
+	}
+	return nil
+}
+
+// fileHistory returns entries for each version of obj in which the file at the
+// logical path name exists or was removed. Renames are followed using
+// diff.Diff's digest matching. If the file isn't in the head version, the
+// history follows the file from the last version that includes name.
+func fileHistory(obj *ocfl.Object, name string) ([]historyEntry, error) {
+	head := obj.Head().Num()
+	states := make([]ocfl.PathMap, head+1) // states[0] is empty
+	states[0] = ocfl.PathMap{}
+	for n := 1; n <= head; n++ {
+		ver := obj.Version(n)
+		if ver == nil {
+			return nil, fmt.Errorf("inventory is missing entry for version %d", n)
+		}
+		states[n] = ver.State().PathMap()
+	}
+	diffs := make([]diff.Result, head+1) // diffs[n] is changes from n-1 to n
+	for n := 1; n <= head; n++ {
+		var err error
+		if diffs[n], err = diff.Diff(states[n-1], states[n]); err != nil {
+			return nil, err
+		}
+	}
+	last := 0
+	for n := head; n > 0; n-- {
+		if _, ok := states[n][name]; ok {
+			last = n
+			break
+		}
+	}
+	if last == 0 {
+		return nil, fmt.Errorf("%q not found in any version of the object", name)
+	}
+	// follow renames forward to find the file's name in the head version
+	cur := name
+	for n := last + 1; n <= head; n++ {
+		if dst, ok := diffs[n].Renamed[cur]; ok {
+			cur = dst
+		}
+	}
+	// walk back from head
+	var history []historyEntry
+	for n := head; n > 0; n-- {
+		ver := obj.Version(n)
+		entry := historyEntry{
+			Version: ver.VNum().String(),
+			Created: ver.Created(),
+			User:    ver.User(),
+			Message: ver.Message(),
+			Path:    cur,
+			Digest:  states[n][cur],
+		}
+		d := diffs[n]
+		_, exists := states[n][cur]
+		switch {
+		case !exists && slices.Contains(d.Removed, cur):
+			entry.Status = historyRemoved
+		case !exists:
+			continue
+		case slices.Contains(d.Added, cur):
+			entry.Status = historyAdded
+		case slices.Contains(d.Modified, cur):
+			entry.Status = historyModified
+		default:
+			entry.Status = historyUnchanged
+			for src, dst := range d.Renamed {
+				if dst == cur {
+					entry.Status = historyRenamed
+					entry.RenamedFrom = src
+					cur = src
+					break
+				}
+			}
+		}
+		history = append(history, entry)
+	}
+	slices.Reverse(history)
+	return history, nil
+}
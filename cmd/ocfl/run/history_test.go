@@ -0,0 +1,102 @@
+package run_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/testutil"
+)
+
+func TestHistory(t *testing.T) {
+	_, fixtures := testutil.TempDirTestData(t,
+		`testdata/object-fixtures/1.1/good-objects/spec-ex-full`,
+		`testdata/store-fixtures/1.0/good-stores/reg-extension-dir-root`,
+	)
+	obj := fixtures[0]
+	root := fixtures[1]
+
+	t.Run("removed and reinstated", func(t *testing.T) {
+		args := []string{`history`, `--object`, obj, `image.tiff`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			lines := strings.Split(strings.TrimSpace(stdout), "\n")
+			be.Equal(t, 3, len(lines))
+			be.In(t, "added", lines[0])
+			be.In(t, "Alice", lines[0])
+			be.In(t, "removed", lines[1])
+			be.In(t, "added", lines[2])
+		})
+	})
+
+	t.Run("json", func(t *testing.T) {
+		args := []string{`history`, `--object`, obj, `foo/bar.xml`, `--json`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			var statuses []string
+			for _, line := range strings.Split(strings.TrimSpace(stdout), "\n") {
+				var entry struct {
+					Version string `json:"version"`
+					Status  string `json:"status"`
+					Digest  string `json:"digest"`
+				}
+				be.NilErr(t, json.Unmarshal([]byte(line), &entry))
+				be.Nonzero(t, entry.Digest)
+				statuses = append(statuses, entry.Version+" "+entry.Status)
+			}
+			be.AllEqual(t, []string{"v1 added", "v2 modified", "v3 unchanged"}, statuses)
+		})
+	})
+
+	t.Run("export", func(t *testing.T) {
+		dir := t.TempDir()
+		args := []string{`history`, `--object`, obj, `image.tiff`, `--export`, dir}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+		})
+		for _, vnum := range []string{"v1", "v3"} {
+			_, err := os.Stat(filepath.Join(dir, vnum, "image.tiff"))
+			be.NilErr(t, err)
+		}
+		_, err := os.Stat(filepath.Join(dir, "v2"))
+		be.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("renamed", func(t *testing.T) {
+		id := "history-rename"
+		content := t.TempDir()
+		be.NilErr(t, os.WriteFile(filepath.Join(content, "a.txt"), []byte("content"), 0644))
+		args := []string{`commit`, `--root`, root, `--id`, id, `-m`, "first", `-n`, "Tester", content}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+		})
+		be.NilErr(t, os.Rename(filepath.Join(content, "a.txt"), filepath.Join(content, "b.txt")))
+		args = []string{`commit`, `--root`, root, `--id`, id, `-m`, "rename", `-n`, "Tester", content}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+		})
+		for _, name := range []string{"a.txt", "b.txt"} {
+			args = []string{`history`, `--root`, root, `--id`, id, name}
+			testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+				be.NilErr(t, err)
+				lines := strings.Split(strings.TrimSpace(stdout), "\n")
+				be.Equal(t, 2, len(lines))
+				be.In(t, "a.txt", lines[0])
+				be.In(t, "added", lines[0])
+				be.In(t, "b.txt", lines[1])
+				be.In(t, "renamed from a.txt", lines[1])
+			})
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		args := []string{`history`, `--object`, obj, `missing.txt`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.Nonzero(t, err)
+			be.In(t, "not found", err.Error())
+		})
+	})
+}
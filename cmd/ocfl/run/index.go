@@ -0,0 +1,79 @@
+package run
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"runtime"
+	"time"
+
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/rootindex"
+)
+
+const indexHelp = "commands for working with the storage root index used to list objects in http roots"
+
+type IndexCmd struct {
+	Build  IndexBuildCmd  `cmd:"" help:"Write an index of the storage root's objects to the storage root"`
+	Verify IndexVerifyCmd `cmd:"" help:"Check that the storage root index is up to date"`
+}
+
+// index build
+type IndexBuildCmd struct {
+	Jobs int `name:"jobs" short:"j" default:"0" help:"number of objects to read concurrently. Defaults to the number of CPU cores."`
+}
+
+func (cmd *IndexBuildCmd) Run(g *globals) error {
+	jobs := cmd.Jobs
+	if jobs < 1 {
+		jobs = runtime.NumCPU()
+	}
+	root, err := g.getRoot()
+	if err != nil {
+		return err
+	}
+	idx, err := rootindex.Build(g.ctx, root, jobs)
+	if err != nil {
+		return err
+	}
+	if err := idx.Write(g.ctx, root.FS(), root.Path()); err != nil {
+		return fmt.Errorf("writing storage root index: %w", err)
+	}
+	g.logger.Info("storage root index written", "path", rootindex.Path, "objects", len(idx.Objects))
+	return nil
+}
+
+// index verify
+type IndexVerifyCmd struct {
+	Jobs int `name:"jobs" short:"j" default:"0" help:"number of objects to read concurrently. Defaults to the number of CPU cores."`
+}
+
+func (cmd *IndexVerifyCmd) Run(g *globals) error {
+	jobs := cmd.Jobs
+	if jobs < 1 {
+		jobs = runtime.NumCPU()
+	}
+	root, err := g.getRoot()
+	if err != nil {
+		return err
+	}
+	idx, err := rootindex.Read(g.ctx, root.FS(), root.Path())
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return errors.New("the storage root doesn't have an index: use 'ocfl index build' to create one")
+		}
+		return fmt.Errorf("reading storage root index: %w", err)
+	}
+	current, err := rootindex.Build(g.ctx, root, jobs)
+	if err != nil {
+		return fmt.Errorf("index may be stale: %w", err)
+	}
+	changes := idx.Compare(current)
+	for _, change := range changes {
+		fmt.Fprintln(g.stdout, change)
+	}
+	if len(changes) > 0 {
+		return fmt.Errorf("storage root index is stale (created %s): rebuild it with 'ocfl index build'", idx.Created.Format(time.RFC3339))
+	}
+	g.logger.Info("storage root index is up to date", "objects", len(idx.Objects))
+	return nil
+}
@@ -0,0 +1,106 @@
+package run_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/testutil"
+)
+
+func TestIndex(t *testing.T) {
+	tmp, fixtures := testutil.TempDirTestData(t,
+		`testdata/store-fixtures/1.0/good-stores/reg-extension-dir-root`,
+		`testdata/content-fixture`,
+	)
+	rootPath := fixtures[0]
+	contentPath := fixtures[1]
+	srv := httptest.NewServer(http.FileServer(http.Dir(tmp)))
+	defer srv.Close()
+	rootURL := srv.URL + "/" + filepath.ToSlash(must(filepath.Rel(tmp, rootPath)))
+
+	t.Run("verify without index", func(t *testing.T) {
+		testutil.RunCLI([]string{"index", "verify", "--root", rootPath}, nil, func(err error, stdout string, stderr string) {
+			be.True(t, err != nil)
+			be.In(t, "ocfl index build", stderr)
+		})
+	})
+	t.Run("http root without index", func(t *testing.T) {
+		testutil.RunCLI([]string{"ls", "--root", rootURL}, nil, func(err error, stdout string, stderr string) {
+			be.True(t, err != nil)
+			be.In(t, "ocfl index build", stderr)
+		})
+	})
+	t.Run("build", func(t *testing.T) {
+		testutil.RunCLI([]string{"index", "build", "--root", rootPath}, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+		})
+		_, err := os.Stat(filepath.Join(rootPath, "extensions", "ocfl-tools-index", "index.json"))
+		be.NilErr(t, err)
+		testutil.RunCLI([]string{"index", "verify", "--root", rootPath}, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.Equal(t, "", stdout)
+		})
+		// the index extension doesn't cause validation warnings
+		testutil.RunCLI([]string{"validate", "--root", rootPath}, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.NotIn(t, "W016", stderr)
+		})
+	})
+	t.Run("http root with index", func(t *testing.T) {
+		testutil.RunCLI([]string{"ls", "--root", rootURL}, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.Equal(t, "ark:123/abc\n", stdout)
+		})
+		testutil.RunCLI([]string{"validate", "--root", rootURL}, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.NotIn(t, "W016", stderr)
+		})
+		testutil.RunCLI([]string{"index", "verify", "--root", rootURL}, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+		})
+	})
+	t.Run("stale index", func(t *testing.T) {
+		args := []string{"commit", "--root", rootPath, "--id", "ark:123/abc", "-m", "update", "-n", "Tester", "-e", "tester@example.com", contentPath}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+		})
+		args = []string{"commit", "--root", rootPath, "--id", "new-object", "-m", "new", "-n", "Tester", "-e", "tester@example.com", contentPath}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+		})
+		testutil.RunCLI([]string{"index", "verify", "--root", rootPath}, nil, func(err error, stdout string, stderr string) {
+			be.True(t, err != nil)
+			be.In(t, "index is stale", stderr)
+			be.In(t, "object updated: ark:123/abc (v1 -> v2)", stdout)
+			be.In(t, "object not in index: new-object", stdout)
+		})
+		// the http root only includes indexed objects
+		testutil.RunCLI([]string{"ls", "--root", rootURL}, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.Equal(t, "ark:123/abc\n", stdout)
+		})
+		testutil.RunCLI([]string{"index", "verify", "--root", rootURL}, nil, func(err error, stdout string, stderr string) {
+			be.True(t, err != nil)
+			be.In(t, "object updated: ark:123/abc (v1 -> v2)", stdout)
+		})
+		testutil.RunCLI([]string{"index", "build", "--root", rootPath}, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+		})
+		testutil.RunCLI([]string{"ls", "--root", rootURL}, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "ark:123/abc\n", stdout)
+			be.In(t, "new-object\n", stdout)
+		})
+	})
+}
+
+func must[T any](val T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return val
+}
@@ -1,9 +1,20 @@
 package run
 
 import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/srerickson/ocfl-go"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
 )
 
 const infoHelp = "Show information about an object or the active storage root"
@@ -11,6 +22,39 @@ const infoHelp = "Show information about an object or the active storage root"
 type InfoCmd struct {
 	ID      string `name:"id" short:"i" optional:"" help:"The id for object to show information about"`
 	ObjPath string `name:"object" help:"full path to object root. If set, --root and --id are ignored."`
+	JSON    bool   `name:"json" help:"print information as JSON"`
+}
+
+// rootInfo is information about a storage root
+type rootInfo struct {
+	Path         string          `json:"path"`
+	Spec         string          `json:"spec"`
+	Description  string          `json:"description,omitempty"`
+	Layout       string          `json:"layout,omitempty"`
+	LayoutConfig json.RawMessage `json:"layout_config,omitempty"`
+	Extensions   []string        `json:"extensions"`
+}
+
+// objectInfo is information about an object. Logical values are for files in
+// every version state.
+type objectInfo struct {
+	Path             string    `json:"path"`
+	ID               string    `json:"id"`
+	Spec             string    `json:"spec"`
+	DigestAlgorithm  string    `json:"digest_algorithm"`
+	InventoryDigest  string    `json:"inventory_digest"`
+	Head             string    `json:"head"`
+	Versions         int       `json:"versions"`
+	Created          time.Time `json:"created"`
+	HeadCreated      time.Time `json:"head_created"`
+	LogicalFiles     int       `json:"logical_files"`
+	LogicalBytes     int64     `json:"logical_bytes"`
+	ContentFiles     int       `json:"content_files"`
+	ContentBytes     int64     `json:"content_bytes"`
+	FixityAlgorithms []string  `json:"fixity_algorithms"`
+	ContentDirectory string    `json:"content_directory"`
+	Padding          int       `json:"version_padding"`
+	Extensions       []string  `json:"extensions"`
 }
 
 func (cmd *InfoCmd) Run(g *globals) error {
@@ -19,18 +63,126 @@ func (cmd *InfoCmd) Run(g *globals) error {
 		if err != nil {
 			return err
 		}
+		info, err := newRootInfo(g.ctx, root)
+		if err != nil {
+			return err
+		}
+		if cmd.JSON {
+			return printJSON(g.stdout, info)
+		}
 		printRootInfo(root, g.stdout, g.logger)
+		if info.LayoutConfig != nil {
+			fmt.Fprintln(g.stdout, "layout config:", string(info.LayoutConfig))
+		}
+		fmt.Fprintln(g.stdout, "extensions:", cmp.Or(strings.Join(info.Extensions, ", "), "-"))
 		return nil
 	}
 	obj, err := g.newObject(cmd.ID, cmd.ObjPath, ocfl.ObjectMustExist())
 	if err != nil {
 		return err
 	}
-	fmt.Fprintln(g.stdout, "object path:", locationString(obj.FS(), obj.Path()))
-	fmt.Fprintln(g.stdout, "id:", obj.ID())
-	fmt.Fprintln(g.stdout, "digest algorithm:", obj.DigestAlgorithm())
-	fmt.Fprintln(g.stdout, "head:", obj.Head())
-	fmt.Fprintln(g.stdout, "OCFL version:", obj.Spec())
-	fmt.Fprintln(g.stdout, "inventory.json", obj.DigestAlgorithm().ID()+":", obj.InventoryDigest())
+	info, err := newObjectInfo(g.ctx, obj)
+	if err != nil {
+		return err
+	}
+	if cmd.JSON {
+		return printJSON(g.stdout, info)
+	}
+	info.print(g.stdout)
 	return nil
 }
+
+func newRootInfo(ctx context.Context, root *ocfl.Root) (*rootInfo, error) {
+	info := &rootInfo{
+		Path:        locationString(root.FS(), root.Path()),
+		Spec:        string(root.Spec()),
+		Description: root.Description(),
+		Layout:      root.LayoutName(),
+	}
+	if layout := root.Layout(); layout != nil {
+		config, err := json.Marshal(layout)
+		if err != nil {
+			return nil, fmt.Errorf("encoding layout configuration: %w", err)
+		}
+		info.LayoutConfig = config
+	}
+	var err error
+	info.Extensions, err = extensionNames(ctx, root.FS(), path.Join(root.Path(), extensionsDir))
+	if err != nil {
+		return nil, fmt.Errorf("reading storage root extensions: %w", err)
+	}
+	return info, nil
+}
+
+func newObjectInfo(ctx context.Context, obj *ocfl.Object) (*objectInfo, error) {
+	stats, err := newObjectStats(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	info := &objectInfo{
+		Path:             locationString(obj.FS(), obj.Path()),
+		ID:               obj.ID(),
+		Spec:             string(obj.Spec()),
+		DigestAlgorithm:  obj.DigestAlgorithm().ID(),
+		InventoryDigest:  obj.InventoryDigest(),
+		Head:             obj.Head().String(),
+		Versions:         obj.Head().Num(),
+		Created:          obj.Version(1).Created(),
+		HeadCreated:      obj.Version(0).Created(),
+		LogicalFiles:     stats.LogicalFiles,
+		LogicalBytes:     stats.LogicalBytes,
+		ContentFiles:     stats.ContentFiles,
+		ContentBytes:     stats.ContentBytes,
+		FixityAlgorithms: obj.FixityAlgorithms(),
+		ContentDirectory: obj.ContentDirectory(),
+		Padding:          obj.Head().Padding(),
+	}
+	if info.FixityAlgorithms == nil {
+		info.FixityAlgorithms = []string{}
+	}
+	slices.Sort(info.FixityAlgorithms)
+	info.Extensions, err = extensionNames(ctx, obj.FS(), path.Join(obj.Path(), extensionsDir))
+	if err != nil {
+		return nil, fmt.Errorf("reading object extensions: %w", err)
+	}
+	return info, nil
+}
+
+func (info *objectInfo) print(w io.Writer) {
+	fmt.Fprintln(w, "object path:", info.Path)
+	fmt.Fprintln(w, "id:", info.ID)
+	fmt.Fprintln(w, "digest algorithm:", info.DigestAlgorithm)
+	fmt.Fprintln(w, "head:", info.Head)
+	fmt.Fprintln(w, "OCFL version:", info.Spec)
+	fmt.Fprintln(w, "inventory.json", info.DigestAlgorithm+":", info.InventoryDigest)
+	fmt.Fprintln(w, "versions:", info.Versions)
+	fmt.Fprintln(w, "created:", info.Created.Format(time.RFC3339))
+	fmt.Fprintln(w, "head created:", info.HeadCreated.Format(time.RFC3339))
+	fmt.Fprintf(w, "logical: %d file(s), %s\n", info.LogicalFiles, formatBytes(info.LogicalBytes))
+	fmt.Fprintf(w, "content: %d file(s), %s\n", info.ContentFiles, formatBytes(info.ContentBytes))
+	fmt.Fprintln(w, "fixity algorithms:", cmp.Or(strings.Join(info.FixityAlgorithms, ", "), "-"))
+	fmt.Fprintln(w, "content directory:", info.ContentDirectory)
+	fmt.Fprintln(w, "version padding:", info.Padding)
+	fmt.Fprintln(w, "extensions:", cmp.Or(strings.Join(info.Extensions, ", "), "-"))
+}
+
+// extensionNames returns the sorted names of directories in the extensions
+// directory dir, which may not exist. If the backend doesn't support listing
+// directories, no names are returned.
+func extensionNames(ctx context.Context, fsys ocflfs.FS, dir string) ([]string, error) {
+	names := []string{}
+	entries, err := ocflfs.ReadDir(ctx, fsys, dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, ocflfs.ErrOpUnsupported) {
+			return names, nil
+		}
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
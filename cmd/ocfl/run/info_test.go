@@ -1,6 +1,10 @@
 package run_test
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"path/filepath"
 	"testing"
 
@@ -49,4 +53,62 @@ func TestInfo(t *testing.T) {
 			be.In(t, fixture, stdout)
 		})
 	})
+	t.Run("object json", func(t *testing.T) {
+		obj := filepath.Join(goodObjectFixtures, `minimal_content_dir_called_stuff`)
+		args := []string{`info`, `--object`, obj, `--json`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			var info struct {
+				Versions         int      `json:"versions"`
+				ContentDirectory string   `json:"content_directory"`
+				ContentFiles     int      `json:"content_files"`
+				FixityAlgorithms []string `json:"fixity_algorithms"`
+				Extensions       []string `json:"extensions"`
+			}
+			be.NilErr(t, json.Unmarshal([]byte(stdout), &info))
+			be.Equal(t, 1, info.Versions)
+			be.Equal(t, "stuff", info.ContentDirectory)
+			be.Equal(t, 1, info.ContentFiles)
+			be.Equal(t, 0, len(info.Extensions))
+		})
+		obj = filepath.Join(goodObjectFixtures, `spec-ex-full`)
+		args = []string{`info`, `--object`, obj}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "versions: 3", stdout)
+			be.In(t, "created: 2018-01-01T01:01:01Z", stdout)
+			be.In(t, "head created: 2018-03-03T03:03:03Z", stdout)
+			be.In(t, "fixity algorithms: md5, sha1", stdout)
+		})
+	})
+	t.Run("storage root json", func(t *testing.T) {
+		args := []string{`info`, `--root`, filepath.Join(goodStoreFixtures, `reg-extension-dir-root`), `--json`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			var info struct {
+				Spec         string         `json:"spec"`
+				Layout       string         `json:"layout"`
+				LayoutConfig map[string]any `json:"layout_config"`
+				Extensions   []string       `json:"extensions"`
+				Description  string         `json:"description"`
+			}
+			be.NilErr(t, json.Unmarshal([]byte(stdout), &info))
+			be.Equal(t, "1.0", info.Spec)
+			be.Equal(t, "0003-hash-and-id-n-tuple-storage-layout", info.Layout)
+			be.Equal(t, "sha256", info.LayoutConfig["digestAlgorithm"].(string))
+			be.AllEqual(t, []string{"0003-hash-and-id-n-tuple-storage-layout"}, info.Extensions)
+			be.Nonzero(t, info.Description)
+		})
+	})
+	t.Run("object over http", func(t *testing.T) {
+		srv := httptest.NewServer(http.FileServer(http.FS(testutil.TestDataFS())))
+		defer srv.Close()
+		objURL, err := url.JoinPath(srv.URL, "testdata", "object-fixtures", "1.1", "good-objects", "spec-ex-full")
+		be.NilErr(t, err)
+		args := []string{`info`, `--object`, objURL}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "content: 4 file(s), 2.5 KiB", stdout)
+		})
+	})
 }
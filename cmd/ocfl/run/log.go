@@ -1,10 +1,16 @@
 package run
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/srerickson/ocfl-go"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/diff"
 )
 
 const logHelp = "Show an object's revision log"
@@ -12,24 +18,202 @@ const logHelp = "Show an object's revision log"
 type LogCmd struct {
 	ID      string `name:"id" short:"i" help:"The id for object to show revision logs from"`
 	ObjPath string `name:"object" help:"full path to object root. If set, --root and --id are ignored."`
+	Path    string `name:"path" short:"p" help:"only show versions that changed files matching the logical path, directory, or glob pattern"`
+	Stat    bool   `name:"stat" help:"show the number of files added, modified, removed, and renamed in each version"`
+	Since   string `name:"since" help:"only show versions created on or after the date (YYYY-MM-DD or RFC3339)"`
+	Until   string `name:"until" help:"only show versions created on or before the date (YYYY-MM-DD or RFC3339)"`
+	User    string `name:"user" help:"only show versions with a user name or address containing the value"`
+	JSON    bool   `name:"json" help:"print versions as JSON Lines"`
+}
+
+// logEntry is a version in the log's JSON output
+type logEntry struct {
+	Version string     `json:"version"`
+	Created time.Time  `json:"created"`
+	Message string     `json:"message"`
+	User    *ocfl.User `json:"user,omitempty"`
+	Stat    *logStat   `json:"stat,omitempty"`
+	Changes *logChange `json:"changes,omitempty"`
+}
+
+// logStat is the number of changed files in a version.
+type logStat struct {
+	Added    int `json:"added"`
+	Modified int `json:"modified"`
+	Removed  int `json:"removed"`
+	Renamed  int `json:"renamed"`
+}
+
+// logChange lists the changed files in a version that match the log's path
+// filter.
+type logChange struct {
+	Added    []string          `json:"added"`
+	Modified []string          `json:"modified"`
+	Removed  []string          `json:"removed"`
+	Renamed  map[string]string `json:"renamed"`
 }
 
 func (cmd *LogCmd) Run(g *globals) error {
+	since, err := parseLogTime(cmd.Since, false)
+	if err != nil {
+		return fmt.Errorf("invalid --since value: %w", err)
+	}
+	until, err := parseLogTime(cmd.Until, true)
+	if err != nil {
+		return fmt.Errorf("invalid --until value: %w", err)
+	}
 	obj, err := g.newObject(cmd.ID, cmd.ObjPath, ocfl.ObjectMustExist())
 	if err != nil {
 		return err
 	}
+	enc := json.NewEncoder(g.stdout)
+	prevState := ocfl.PathMap{}
 	for _, vnum := range obj.Head().Lineage() {
 		version := obj.Version(vnum.Num())
 		if version == nil {
 			return errors.New("inventory is missing entry for " + vnum.String())
 		}
+		state := version.State().PathMap()
+		var changes diff.Result
+		if cmd.Path != "" || cmd.Stat {
+			changes, err = diff.Diff(prevState, state)
+			if err != nil {
+				return err
+			}
+		}
+		prevState = state
+		if !since.IsZero() && version.Created().Before(since) {
+			continue
+		}
+		if !until.IsZero() && version.Created().After(until) {
+			continue
+		}
+		if cmd.User != "" && !matchUser(version.User(), cmd.User) {
+			continue
+		}
+		var pathChanges diff.Result
+		if cmd.Path != "" {
+			pathChanges = filterDiff(changes, func(name string) bool {
+				return matchLogPath(cmd.Path, name)
+			})
+			if pathChanges.Empty() {
+				continue
+			}
+		}
+		if cmd.JSON {
+			entry := logEntry{
+				Version: vnum.String(),
+				Created: version.Created(),
+				Message: version.Message(),
+				User:    version.User(),
+			}
+			if cmd.Stat {
+				entry.Stat = &logStat{
+					Added:    len(changes.Added),
+					Modified: len(changes.Modified),
+					Removed:  len(changes.Removed),
+					Renamed:  len(changes.Renamed),
+				}
+			}
+			if cmd.Path != "" {
+				entry.Changes = &logChange{
+					Added:    append([]string{}, pathChanges.Added...),
+					Modified: append([]string{}, pathChanges.Modified...),
+					Removed:  append([]string{}, pathChanges.Removed...),
+					Renamed:  map[string]string{},
+				}
+				maps.Copy(entry.Changes.Renamed, pathChanges.Renamed)
+			}
+			if err := enc.Encode(entry); err != nil {
+				return err
+			}
+			continue
+		}
 		fmt.Fprintf(g.stdout, "%s (%s): %q", vnum.String(), version.Created(), version.Message())
 		if version.User() != nil {
 			fmt.Fprintf(g.stdout, " %s <%s>", version.User().Name, version.User().Address)
 		}
 		fmt.Fprintln(g.stdout, "")
+		if cmd.Stat {
+			fmt.Fprintf(g.stdout, "    %d added, %d modified, %d removed, %d renamed\n",
+				len(changes.Added), len(changes.Modified), len(changes.Removed), len(changes.Renamed))
+		}
+		if cmd.Path != "" {
+			for _, line := range strings.Split(strings.TrimSpace(pathChanges.String()), "\n") {
+				fmt.Fprintln(g.stdout, "   ", line)
+			}
+		}
 	}
 	return nil
+}
+
+// parseLogTime parses a date (YYYY-MM-DD) or RFC3339 timestamp. If endOfDay is
+// true, dates are parsed as the last moment of the day.
+func parseLogTime(val string, endOfDay bool) (time.Time, error) {
+	if val == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, val); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, val)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected YYYY-MM-DD or RFC3339 format: %q", val)
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
+// matchUser returns true if the user's name or address includes val (ignoring
+// case).
+func matchUser(user *ocfl.User, val string) bool {
+	if user == nil {
+		return false
+	}
+	val = strings.ToLower(val)
+	return strings.Contains(strings.ToLower(user.Name), val) ||
+		strings.Contains(strings.ToLower(user.Address), val)
+}
+
+// matchLogPath returns true if name is the logical path pattern, is in the
+// directory pattern, or matches the glob pattern.
+func matchLogPath(pattern string, name string) bool {
+	pattern = strings.TrimSuffix(pattern, "/")
+	if name == pattern || strings.HasPrefix(name, pattern+"/") {
+		return true
+	}
+	matched, _ := path.Match(pattern, name)
+	return matched
+}
 
+// filterDiff returns the subset of changes in result with paths for which
+// match returns true. Renames are included if either path matches.
+func filterDiff(result diff.Result, match func(string) bool) diff.Result {
+	var filtered diff.Result
+	for _, name := range result.Added {
+		if match(name) {
+			filtered.Added = append(filtered.Added, name)
+		}
+	}
+	for _, name := range result.Modified {
+		if match(name) {
+			filtered.Modified = append(filtered.Modified, name)
+		}
+	}
+	for _, name := range result.Removed {
+		if match(name) {
+			filtered.Removed = append(filtered.Removed, name)
+		}
+	}
+	for src, dst := range result.Renamed {
+		if match(src) || match(dst) {
+			if filtered.Renamed == nil {
+				filtered.Renamed = map[string]string{}
+			}
+			filtered.Renamed[src] = dst
+		}
+	}
+	return filtered
 }
@@ -1,6 +1,7 @@
 package run_test
 
 import (
+	"encoding/json"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -37,6 +38,45 @@ func TestLog(t *testing.T) {
 			be.In(t, "Reinstate image.tiff, delete empty.txt", lines[2])
 		})
 	})
+	t.Run("--path", func(t *testing.T) {
+		obj := filepath.Join(goodObjectFixtures, "spec-ex-full")
+		args := []string{`log`, `--object`, obj, `--path`, `foo/bar.xml`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "v1 ", stdout)
+			be.In(t, "v2 ", stdout)
+			be.False(t, strings.Contains(stdout, "v3 "))
+		})
+		args = []string{`log`, `--object`, obj, `--path`, `*.tiff`, `--stat`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "1 added, 1 modified, 1 removed, 0 renamed", stdout)
+			be.In(t, "rem: image.tiff", stdout)
+		})
+	})
+	t.Run("--json with filters", func(t *testing.T) {
+		obj := filepath.Join(goodObjectFixtures, "spec-ex-full")
+		args := []string{`log`, `--object`, obj, `--json`, `--stat`, `--since`, `2018-02-01`, `--user`, `bob`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			lines := strings.Split(strings.TrimSpace(stdout), "\n")
+			be.Equal(t, 1, len(lines))
+			var entry struct {
+				Version string `json:"version"`
+				Stat    struct {
+					Modified int `json:"modified"`
+				} `json:"stat"`
+			}
+			be.NilErr(t, json.Unmarshal([]byte(lines[0]), &entry))
+			be.Equal(t, "v2", entry.Version)
+			be.Equal(t, 1, entry.Stat.Modified)
+		})
+		args = []string{`log`, `--object`, obj, `--until`, `2018-02-02`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.Equal(t, 2, len(strings.Split(strings.TrimSpace(stdout), "\n")))
+		})
+	})
 	t.Run("missing args", func(t *testing.T) {
 		testutil.RunCLI([]string{"log"}, nil, func(err error, stdout string, stderr string) {
 			be.True(t, err != nil)
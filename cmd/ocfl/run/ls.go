@@ -1,9 +1,19 @@
 package run
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/srerickson/ocfl-go"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
 )
 
 const lsHelp = "List objects in a storage root or files in an object"
@@ -13,22 +23,34 @@ type LsCmd struct {
 	ObjPath     string `name:"object" help:"full path to object root. If set, --root and --id are ignored."`
 	Version     int    `name:"version" short:"v" default:"0" help:"The object version number (unpadded) to list contents from. The default (0) lists the latest version."`
 	WithDigests bool   `name:"digests" short:"d" help:"Show digests when listing contents of an object version."`
+	Long        bool   `name:"long" short:"l" help:"Show details: head, last modified, file count, and size for objects; size, content path, version added, and fixity for files."`
+	JSON        bool   `name:"json" help:"Print the listing as JSON Lines."`
+	Glob        string `name:"glob" help:"Only list files with paths matching the glob pattern (e.g., '*.txt' or 'data/*.csv')."`
+	Prefix      string `name:"prefix" help:"Only list files in the directory."`
+}
+
+// lsObject is an object in a long or JSON root listing.
+type lsObject struct {
+	ID           string     `json:"id"`
+	Head         string     `json:"head,omitempty"`
+	LastModified *time.Time `json:"last_modified,omitempty"`
+	Files        *int       `json:"files,omitempty"`
+	Size         *int64     `json:"size,omitempty"`
+}
+
+// lsFile is a file in a long or JSON object version listing.
+type lsFile struct {
+	Path        string            `json:"path"`
+	Digest      string            `json:"digest,omitempty"`
+	Size        *int64            `json:"size,omitempty"`
+	ContentPath string            `json:"content_path,omitempty"`
+	Added       string            `json:"added,omitempty"` // version in which the content was added
+	Fixity      map[string]string `json:"fixity,omitempty"`
 }
 
 func (cmd *LsCmd) Run(g *globals) error {
 	if cmd.ID == "" && cmd.ObjPath == "" {
-		// list object ids in root
-		root, err := g.getRoot()
-		if err != nil {
-			return err
-		}
-		for obj, err := range root.Objects(g.ctx) {
-			if err != nil {
-				return fmt.Errorf("while listing objects in root: %w", err)
-			}
-			fmt.Fprintln(g.stdout, obj.ID())
-		}
-		return nil
+		return cmd.listObjects(g)
 	}
 	obj, err := g.newObject(cmd.ID, cmd.ObjPath, ocfl.ObjectMustExist())
 	if err != nil {
@@ -39,12 +61,153 @@ func (cmd *LsCmd) Run(g *globals) error {
 		err := fmt.Errorf("version %d not found in object %q", cmd.Version, cmd.ID)
 		return err
 	}
-	for path, digest := range ver.State().PathMap().SortedPaths() {
-		if cmd.WithDigests {
-			fmt.Fprintln(g.stdout, digest, path)
+	var enc *json.Encoder
+	var tw *tabwriter.Writer
+	switch {
+	case cmd.JSON:
+		enc = json.NewEncoder(g.stdout)
+	case cmd.Long:
+		tw = tabwriter.NewWriter(g.stdout, 0, 4, 2, ' ', 0)
+		defer tw.Flush()
+	}
+	for name, digest := range ver.State().PathMap().SortedPaths() {
+		if !cmd.match(name) {
+			continue
+		}
+		if enc == nil && tw == nil {
+			if cmd.WithDigests {
+				fmt.Fprintln(g.stdout, digest, name)
+				continue
+			}
+			fmt.Fprintln(g.stdout, name)
+			continue
+		}
+		file := lsFile{Path: name}
+		if cmd.WithDigests || cmd.Long {
+			file.Digest = digest
+		}
+		if cmd.Long {
+			if err := file.setDetails(g.ctx, obj, digest); err != nil {
+				return err
+			}
+		}
+		if enc != nil {
+			if err := enc.Encode(file); err != nil {
+				return err
+			}
+			continue
+		}
+		file.printLong(tw, cmd.WithDigests)
+	}
+	return nil
+}
+
+func (cmd *LsCmd) listObjects(g *globals) error {
+	root, err := g.getRoot()
+	if err != nil {
+		return err
+	}
+	var tw *tabwriter.Writer
+	if cmd.Long && !cmd.JSON {
+		tw = tabwriter.NewWriter(g.stdout, 0, 4, 2, ' ', 0)
+		defer tw.Flush()
+	}
+	enc := json.NewEncoder(g.stdout)
+	for obj, err := range root.Objects(g.ctx) {
+		if err != nil {
+			return fmt.Errorf("while listing objects in root: %w", err)
+		}
+		if !cmd.Long && !cmd.JSON {
+			fmt.Fprintln(g.stdout, obj.ID())
+			continue
+		}
+		item := lsObject{ID: obj.ID()}
+		if cmd.Long {
+			if err := item.setDetails(g.ctx, obj); err != nil {
+				return err
+			}
+		}
+		if cmd.JSON {
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
 			continue
 		}
-		fmt.Fprintln(g.stdout, path)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", item.ID, item.Head,
+			item.LastModified.Format(time.RFC3339), *item.Files, formatBytes(*item.Size))
+	}
+	return nil
+}
+
+// match returns true if the logical path name matches the command's glob and
+// prefix filters.
+func (cmd *LsCmd) match(name string) bool {
+	if prefix := strings.Trim(cmd.Prefix, "/"); prefix != "" && prefix != "." {
+		if !strings.HasPrefix(name, prefix+"/") {
+			return false
+		}
+	}
+	if cmd.Glob != "" {
+		matched, _ := path.Match(cmd.Glob, name)
+		return matched
+	}
+	return true
+}
+
+// setDetails sets the object's head, last modified time (the head version's
+// created time), number of files in the head version, and the total size of
+// its content.
+func (item *lsObject) setDetails(ctx context.Context, obj *ocfl.Object) error {
+	head := obj.Version(0)
+	sizes, err := contentFileSizes(ctx, obj)
+	if err != nil {
+		return fmt.Errorf("reading content sizes for object %q: %w", obj.ID(), err)
 	}
+	var size int64
+	for name := range obj.Manifest().Paths() {
+		size += sizes[name]
+	}
+	created := head.Created()
+	files := head.State().NumPaths()
+	item.Head = obj.Head().String()
+	item.LastModified = &created
+	item.Files = &files
+	item.Size = &size
 	return nil
 }
+
+// setDetails sets the file's content path, size, the version in which the
+// content was added, and its fixity.
+func (file *lsFile) setDetails(ctx context.Context, obj *ocfl.Object, digest string) error {
+	contentPaths := obj.Manifest()[digest]
+	if len(contentPaths) == 0 {
+		return fmt.Errorf("no content path for %q in object %q", file.Path, obj.ID())
+	}
+	file.ContentPath = slices.Min(contentPaths)
+	file.Added, _, _ = strings.Cut(file.ContentPath, "/")
+	info, err := ocflfs.StatFile(ctx, obj.FS(), path.Join(obj.Path(), file.ContentPath))
+	if err != nil {
+		return fmt.Errorf("reading size of %q in object %q: %w", file.Path, obj.ID(), err)
+	}
+	size := info.Size()
+	file.Size = &size
+	if fixity := obj.GetFixity(digest); len(fixity) > 0 {
+		file.Fixity = fixity
+	}
+	return nil
+}
+
+func (file *lsFile) printLong(w io.Writer, withDigest bool) {
+	var fixity []string
+	for _, alg := range slices.Sorted(maps.Keys(file.Fixity)) {
+		fixity = append(fixity, alg+":"+file.Fixity[alg])
+	}
+	fixityStr := strings.Join(fixity, ",")
+	if fixityStr == "" {
+		fixityStr = "-"
+	}
+	if withDigest {
+		fmt.Fprintf(w, "%s\t", file.Digest)
+	}
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", formatBytes(*file.Size), file.Added, file.ContentPath, fixityStr, file.Path)
+}
@@ -1,9 +1,11 @@
 package run_test
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/carlmjohnson/be"
@@ -31,4 +33,93 @@ func TestLS(t *testing.T) {
 		})
 
 	})
+	t.Run("root url", func(t *testing.T) {
+		srv := httptest.NewServer(http.FileServer(http.FS(testutil.TestDataFS())))
+		defer srv.Close()
+		rootURL, err := url.JoinPath(srv.URL, "testdata", "store-fixtures", "1.0", "good-stores", "reg-extension-dir-root")
+		be.NilErr(t, err)
+		cmd := []string{"ls", "--root", rootURL, "--id", "ark:123/abc"}
+		testutil.RunCLI(cmd, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, `a_file.txt`, stdout)
+		})
+		// objects can't be listed without an index
+		cmd = []string{"ls", "--root", rootURL}
+		testutil.RunCLI(cmd, nil, func(err error, stdout string, stderr string) {
+			be.True(t, err != nil)
+		})
+	})
+	t.Run("object url with token", func(t *testing.T) {
+		fileSrv := http.FileServer(http.FS(testutil.TestDataFS()))
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer s3cr3t" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			fileSrv.ServeHTTP(w, r)
+		}))
+		defer srv.Close()
+		objURL, err := url.JoinPath(srv.URL, "testdata", "object-fixtures", "1.1", "good-objects", "spec-ex-full")
+		be.NilErr(t, err)
+		cmd := []string{"ls", "--object", objURL}
+		testutil.RunCLI(cmd, map[string]string{"OCFL_HTTP_TOKEN": "s3cr3t"}, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, `foo/bar.xml`, stdout)
+		})
+		testutil.RunCLI(cmd, nil, func(err error, stdout string, stderr string) {
+			be.True(t, err != nil)
+			be.In(t, "401 Unauthorized", stderr)
+		})
+	})
+	t.Run("object long", func(t *testing.T) {
+		srv := httptest.NewServer(http.FileServer(http.FS(testutil.TestDataFS())))
+		defer srv.Close()
+		objURL, err := url.JoinPath(srv.URL, "testdata", "object-fixtures", "1.1", "good-objects", "spec-ex-full")
+		be.NilErr(t, err)
+		cmd := []string{"ls", "--object", objURL, "--long"}
+		testutil.RunCLI(cmd, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "272 B    v2  v2/content/foo/bar.xml  md5:2673a7b11a70bc7ff960ad8127b4adeb", stdout)
+		})
+	})
+	t.Run("json with filters", func(t *testing.T) {
+		_, fixtures := testutil.TempDirTestData(t, `testdata/object-fixtures/1.1/good-objects/spec-ex-full`)
+		obj := fixtures[0]
+		cmd := []string{"ls", "--object", obj, "--json", "--long", "--glob", "*.tiff"}
+		testutil.RunCLI(cmd, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			lines := strings.Split(strings.TrimSpace(stdout), "\n")
+			be.Equal(t, 1, len(lines))
+			var file struct {
+				Path        string            `json:"path"`
+				Size        int64             `json:"size"`
+				ContentPath string            `json:"content_path"`
+				Added       string            `json:"added"`
+				Fixity      map[string]string `json:"fixity"`
+			}
+			be.NilErr(t, json.Unmarshal([]byte(lines[0]), &file))
+			be.Equal(t, "image.tiff", file.Path)
+			be.Equal(t, "v1/content/image.tiff", file.ContentPath)
+			be.Equal(t, "v1", file.Added)
+			be.Equal(t, "c289c8ccd4bab6e385f5afdd89b5bda2", file.Fixity["md5"])
+		})
+		cmd = []string{"ls", "--object", obj, "--prefix", "foo/"}
+		testutil.RunCLI(cmd, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.Equal(t, "foo/bar.xml\n", stdout)
+		})
+	})
+	t.Run("root long", func(t *testing.T) {
+		_, fixtures := testutil.TempDirTestData(t, `testdata/store-fixtures/1.0/good-stores/reg-extension-dir-root`)
+		cmd := []string{"ls", "--root", fixtures[0], "--long"}
+		testutil.RunCLI(cmd, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.Equal(t, "ark:123/abc  v1  2019-01-01T02:03:04Z  1  20 B\n", stdout)
+		})
+		cmd = []string{"ls", "--root", fixtures[0], "--json", "--long"}
+		testutil.RunCLI(cmd, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.Equal(t, `{"id":"ark:123/abc","head":"v1","last_modified":"2019-01-01T02:03:04Z","files":1,"size":20}`+"\n", stdout)
+		})
+	})
 }
@@ -0,0 +1,163 @@
+package run
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/srerickson/ocfl-go"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+)
+
+const packHelp = "Write an object, with all its versions, to a zip or tar file"
+
+type PackCmd struct {
+	ID      string `name:"id" short:"i" help:"The ID for the object to pack"`
+	ObjPath string `name:"object" help:"full path to object root. If set, --root and --id are ignored."`
+	Output  string `name:"output" short:"o" required:"" help:"The zip or tar file to create. The format is determined by the file extension (.zip or .tar)."`
+	Replace bool   `name:"replace" help:"replace the output file if it exists"`
+}
+
+func (cmd *PackCmd) Run(g *globals) (err error) {
+	format, err := archiveFormat(cmd.Output)
+	if err != nil {
+		return err
+	}
+	obj, err := g.newObject(cmd.ID, cmd.ObjPath, ocfl.ObjectMustExist())
+	if err != nil {
+		return err
+	}
+	files, err := packFiles(g.ctx, obj)
+	if err != nil {
+		return fmt.Errorf("listing object files: %w", err)
+	}
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !cmd.Replace {
+		flag |= os.O_EXCL
+	}
+	out, err := os.OpenFile(cmd.Output, flag, 0664)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("output file exists (use --replace to overwrite it): %s", cmd.Output)
+		}
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, out.Close())
+		if err != nil {
+			// don't leave an incomplete archive behind
+			os.Remove(cmd.Output)
+		}
+	}()
+	var size int64
+	switch format {
+	case "zip":
+		size, err = writeZip(g.ctx, out, obj, files)
+	default:
+		size, err = writeTar(g.ctx, out, obj, files)
+	}
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", cmd.Output, err)
+	}
+	g.logger.Info("packed object", "object_id", obj.ID(), "versions", obj.Head().Num(), "files", len(files), "size", formatBytes(size), "output", cmd.Output)
+	return nil
+}
+
+// archiveFormat returns the archive format ("zip" or "tar") for the file name.
+func archiveFormat(name string) (string, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".zip":
+		return "zip", nil
+	case ".tar":
+		return "tar", nil
+	}
+	return "", fmt.Errorf("unsupported archive file extension (use .zip or .tar): %s", name)
+}
+
+// packFiles returns all files in the object directory, sorted by path.
+func packFiles(ctx context.Context, obj *ocfl.Object) ([]*ocflfs.FileRef, error) {
+	var files []*ocflfs.FileRef
+	for file, err := range ocflfs.WalkFiles(ctx, obj.FS(), obj.Path()) {
+		if err != nil {
+			return nil, err
+		}
+		if file.Info == nil {
+			if err := file.Stat(ctx); err != nil {
+				return nil, err
+			}
+		}
+		files = append(files, file)
+	}
+	slices.SortFunc(files, func(a, b *ocflfs.FileRef) int { return strings.Compare(a.Path, b.Path) })
+	return files, nil
+}
+
+func writeZip(ctx context.Context, out io.Writer, obj *ocfl.Object, files []*ocflfs.FileRef) (int64, error) {
+	zw := zip.NewWriter(out)
+	var size int64
+	for _, file := range files {
+		hdr := &zip.FileHeader{
+			Name:     file.Path,
+			Method:   zip.Deflate,
+			Modified: file.Info.ModTime(),
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return 0, err
+		}
+		n, err := copyObjectFile(ctx, w, obj, file)
+		if err != nil {
+			return 0, err
+		}
+		size += n
+	}
+	return size, zw.Close()
+}
+
+func writeTar(ctx context.Context, out io.Writer, obj *ocfl.Object, files []*ocflfs.FileRef) (int64, error) {
+	tw := tar.NewWriter(out)
+	var size int64
+	for _, file := range files {
+		hdr := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     file.Path,
+			Mode:     0644,
+			Size:     file.Info.Size(),
+			ModTime:  file.Info.ModTime(),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return 0, err
+		}
+		n, err := copyObjectFile(ctx, tw, obj, file)
+		if err != nil {
+			return 0, err
+		}
+		size += n
+	}
+	return size, tw.Close()
+}
+
+// copyObjectFile copies the contents of the object file to w.
+func copyObjectFile(ctx context.Context, w io.Writer, obj *ocfl.Object, file *ocflfs.FileRef) (int64, error) {
+	f, err := obj.FS().OpenFile(ctx, path.Join(obj.Path(), file.Path))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	n, err := io.Copy(w, f)
+	if err != nil {
+		return n, fmt.Errorf("reading %s: %w", file.Path, err)
+	}
+	if n != file.Info.Size() {
+		return n, fmt.Errorf("reading %s: expected %d bytes, got %d", file.Path, file.Info.Size(), n)
+	}
+	return n, nil
+}
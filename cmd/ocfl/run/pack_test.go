@@ -0,0 +1,102 @@
+package run_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/testutil"
+)
+
+func TestPack(t *testing.T) {
+	tmp, fixtures := testutil.TempDirTestData(t,
+		"testdata/store-fixtures/1.0/good-stores/reg-extension-dir-root",
+		"testdata/object-fixtures/1.1/good-objects/spec-ex-full",
+	)
+	rootFixture := fixtures[0]
+	objFixture := fixtures[1]
+	id := "ark:123/abc"
+	for _, ext := range []string{".zip", ".tar"} {
+		t.Run(ext, func(t *testing.T) {
+			output := filepath.Join(tmp, "object"+ext)
+			args := []string{"pack", "--root", rootFixture, "--id", id, "-o", output}
+			testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+				be.NilErr(t, err)
+				be.In(t, "files=6", stderr)
+			})
+			first, err := os.ReadFile(output)
+			be.NilErr(t, err)
+			// output isn't replaced without --replace
+			testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+				be.True(t, err != nil)
+				be.In(t, "output file exists", stderr)
+			})
+			// packing is deterministic
+			testutil.RunCLI(append(args, "--replace"), nil, func(err error, stdout string, stderr string) {
+				be.NilErr(t, err)
+			})
+			second, err := os.ReadFile(output)
+			be.NilErr(t, err)
+			be.True(t, string(first) == string(second))
+			// the packed object can be read directly
+			objLoc := ext[1:] + "://" + output
+			testutil.RunCLI([]string{"validate", "--object", objLoc}, nil, func(err error, stdout string, stderr string) {
+				be.NilErr(t, err)
+			})
+			// unpack into a new root
+			root := filepath.Join(tmp, "root"+ext)
+			testutil.RunCLI([]string{"init-root", "--root", root, "--layout", "0004-hashed-n-tuple-storage-layout"}, nil, func(err error, stdout string, stderr string) {
+				be.NilErr(t, err)
+			})
+			testutil.RunCLI([]string{"unpack", "--root", root, output}, nil, func(err error, stdout string, stderr string) {
+				be.NilErr(t, err)
+				be.In(t, "unpacked object", stderr)
+			})
+			testutil.RunCLI([]string{"validate", "--root", root, "--id", id}, nil, func(err error, stdout string, stderr string) {
+				be.NilErr(t, err)
+			})
+			testutil.RunCLI([]string{"unpack", "--root", root, output}, nil, func(err error, stdout string, stderr string) {
+				be.True(t, err != nil)
+				be.In(t, "already exists", stderr)
+			})
+		})
+	}
+	t.Run("object path", func(t *testing.T) {
+		output := filepath.Join(tmp, "spec-ex-full.zip")
+		testutil.RunCLI([]string{"pack", "--object", objFixture, "-o", output}, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+		})
+		testutil.RunCLI([]string{"ls", "--object", "zip://" + output, "-v", "1"}, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "image.tiff", stdout)
+		})
+	})
+	t.Run("unsupported extension", func(t *testing.T) {
+		args := []string{"pack", "--root", rootFixture, "--id", id, "-o", filepath.Join(tmp, "object.tgz")}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.True(t, err != nil)
+			be.In(t, "unsupported archive file extension", stderr)
+		})
+	})
+	t.Run("invalid object", func(t *testing.T) {
+		// the object's content doesn't match the inventory
+		objDir := filepath.Join(tmp, "invalid")
+		be.NilErr(t, os.CopyFS(objDir, os.DirFS(objFixture)))
+		be.NilErr(t, os.WriteFile(filepath.Join(objDir, "v1", "content", "image.tiff"), []byte("changed"), 0o644))
+		output := filepath.Join(tmp, "invalid.tar")
+		testutil.ArchiveDir(t, objDir, output, "obj")
+		root := filepath.Join(tmp, "invalid-root")
+		testutil.RunCLI([]string{"init-root", "--root", root, "--layout", "0004-hashed-n-tuple-storage-layout"}, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+		})
+		testutil.RunCLI([]string{"unpack", "--root", root, "--dir", "obj", output}, nil, func(err error, stdout string, stderr string) {
+			be.True(t, err != nil)
+			be.In(t, "not valid", stderr)
+		})
+		testutil.RunCLI([]string{"ls", "--root", root}, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.Equal(t, "", stdout) // nothing installed
+		})
+	})
+}
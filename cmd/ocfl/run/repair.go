@@ -0,0 +1,362 @@
+package run
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/srerickson/ocfl-go"
+	"github.com/srerickson/ocfl-go/digest"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+)
+
+const repairHelp = "Find and fix recoverable problems with an object"
+
+type RepairCmd struct {
+	ID         string `name:"id" short:"i" help:"The ID for the object to repair" required:""`
+	NoConfirm  bool   `name:"yes" short:"y" help:"skip repair confirmation."`
+	SkipDigest bool   `name:"skip-digest" help:"skip digest (checksum) validation"`
+}
+
+// repairFinding is a problem with an object found during repair. Findings
+// that are safe to fix have a non-nil fix function.
+type repairFinding struct {
+	code    string // OCFL validation code
+	problem string
+	fix     func(context.Context) error
+}
+
+func (f *repairFinding) safe() bool { return f.fix != nil }
+
+func (cmd *RepairCmd) Run(g *globals) error {
+	// read files from the storage root, not the cache, to detect damage.
+	g.noCache = true
+	ctx := g.ctx
+	root, err := g.getRoot()
+	if err != nil {
+		return err
+	}
+	objPath, err := root.ResolveID(cmd.ID)
+	if err != nil {
+		return fmt.Errorf("cannot repair %q: %w", cmd.ID, err)
+	}
+	fsys, dir := root.FS(), path.Join(root.Path(), objPath)
+	before := cmd.validate(ctx, fsys, dir)
+	findings, err := inspectObject(ctx, fsys, dir)
+	if err != nil {
+		return fmt.Errorf("cannot repair %q: %w", cmd.ID, err)
+	}
+	// validation errors that aren't addressed by the inspection are not
+	// repairable.
+	for _, verr := range before.Errors() {
+		var validErr *ocfl.ValidationError
+		code := ""
+		if errors.As(verr, &validErr) {
+			code = validErr.Code
+		}
+		covered := slices.ContainsFunc(findings, func(f *repairFinding) bool {
+			return code != "" && f.code == code
+		})
+		if !covered {
+			findings = append(findings, &repairFinding{code: code, problem: verr.Error()})
+		}
+	}
+	fmt.Fprintln(g.stdout, "before repair:", validationSummary(before))
+	var safe int
+	for _, f := range findings {
+		label := "[unsafe]"
+		if f.safe() {
+			label = "[safe]  "
+			safe++
+		}
+		fmt.Fprintln(g.stdout, " ", label, cmp.Or(f.code, "----"), f.problem)
+	}
+	if safe == 0 {
+		fmt.Fprintln(g.stdout, "no safe repairs found")
+		if before.Err() != nil {
+			return errors.New("object has errors that can't be repaired")
+		}
+		return nil
+	}
+	if !cmd.NoConfirm {
+		fmt.Fprintf(g.stdout, "apply %d safe repair(s) to %q? [y/N]: ", safe, cmd.ID)
+		reader := bufio.NewReader(g.stdin)
+		line, err := reader.ReadString('\n')
+		response := strings.ToLower(strings.Trim(line, " \n"))
+		if err != nil || response != "y" {
+			fmt.Fprintln(g.stdout, "object not repaired")
+			return nil
+		}
+	}
+	for _, f := range findings {
+		if !f.safe() {
+			continue
+		}
+		if err := f.fix(ctx); err != nil {
+			return fmt.Errorf("repairing %q: %s: %w", cmd.ID, f.problem, err)
+		}
+		g.logger.Info("repaired", "object_id", cmd.ID, "problem", f.problem)
+	}
+	after := cmd.validate(ctx, fsys, dir)
+	fmt.Fprintln(g.stdout, "after repair: ", validationSummary(after))
+	if after.Err() != nil {
+		return errors.New("object still has errors")
+	}
+	return nil
+}
+
+func (cmd *RepairCmd) validate(ctx context.Context, fsys ocflfs.FS, dir string) *ocfl.Validation {
+	var opts []ocfl.ObjectValidationOption
+	if cmd.SkipDigest {
+		opts = append(opts, ocfl.ValidationSkipDigest())
+	}
+	return &ocfl.ValidateObject(ctx, fsys, dir, opts...).Validation
+}
+
+// inspectObject returns problems found with the object in dir that can be
+// identified from the object's files and inventories, without validating
+// content digests.
+func inspectObject(ctx context.Context, fsys ocflfs.FS, dir string) ([]*repairFinding, error) {
+	// top-level entries in the object root and version directories with
+	// the names of files in them.
+	entries := map[string][]string{}
+	for file, err := range ocflfs.WalkFiles(ctx, fsys, dir) {
+		if err != nil {
+			return nil, err
+		}
+		top, _, _ := strings.Cut(file.Path, "/")
+		entries[top] = append(entries[top], file.Path)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("object not found: %w", fs.ErrNotExist)
+	}
+	var findings []*repairFinding
+	var rootRestored bool
+	rootInv, err := ocfl.ReadInventory(ctx, fsys, dir)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		f := &repairFinding{code: "E063", problem: "root inventory is missing"}
+		rootInv = latestVersionInventory(ctx, fsys, dir, entries)
+		if rootInv != nil {
+			f.problem += fmt.Sprintf(": restore from %s", rootInv.Head)
+			f.fix = func(ctx context.Context) error {
+				return writeInventory(ctx, fsys, dir, rootInv)
+			}
+			rootRestored = true
+		}
+		findings = append(findings, f)
+		if rootInv == nil {
+			return findings, nil
+		}
+	case err != nil:
+		// an invalid root inventory isn't repairable, and it is reported by
+		// validation.
+		return findings, nil
+	}
+	// inventory sidecars
+	if !rootRestored {
+		var headInv *ocfl.StoredInventory
+		if slices.Contains(entries[rootInv.Head.String()], path.Join(rootInv.Head.String(), "inventory.json")) {
+			headInv, _ = ocfl.ReadInventory(ctx, fsys, path.Join(dir, rootInv.Head.String()))
+		}
+		if f := checkSidecar(ctx, fsys, dir, "", rootInv, headInv); f != nil {
+			findings = append(findings, f)
+		}
+	}
+	invAlgs := map[string]string{} // digest algorithms for version inventories
+	for _, vnum := range rootInv.Head.Lineage() {
+		vdir := vnum.String()
+		if !slices.Contains(entries[vdir], path.Join(vdir, "inventory.json")) {
+			continue
+		}
+		inv, err := ocfl.ReadInventory(ctx, fsys, path.Join(dir, vdir))
+		if err != nil {
+			continue
+		}
+		invAlgs[vdir] = inv.DigestAlgorithm
+		var copyInv *ocfl.StoredInventory
+		if vnum == rootInv.Head {
+			copyInv = rootInv
+		}
+		if f := checkSidecar(ctx, fsys, dir, vdir, inv, copyInv); f != nil {
+			findings = append(findings, f)
+		}
+	}
+	// unexpected files: only leftovers from interrupted updates are safe to
+	// remove. Anything else may be data that belongs somewhere else.
+	contentDir := cmp.Or(rootInv.ContentDirectory, "content")
+	for _, top := range slices.Sorted(maps.Keys(entries)) {
+		var vnum ocfl.VNum
+		switch {
+		case top == "inventory.json" ||
+			top == "inventory.json."+rootInv.DigestAlgorithm ||
+			strings.HasPrefix(top, "0=ocfl_object_") ||
+			top == "extensions" ||
+			top == "logs":
+		case isInventoryDebris(top):
+			findings = append(findings, removeFinding(fsys, dir, "E001", top))
+		case ocfl.ParseVNum(top, &vnum) == nil && vnum.Num() <= rootInv.Head.Num():
+			// files in the version directory
+			sidecar := "inventory.json." + cmp.Or(invAlgs[top], rootInv.DigestAlgorithm)
+			unexpected := map[string]bool{}
+			for _, name := range entries[top] {
+				second, _, _ := strings.Cut(strings.TrimPrefix(name, top+"/"), "/")
+				switch {
+				case second == "inventory.json" || second == sidecar:
+				case second == contentDir && name != path.Join(top, second):
+				default:
+					unexpected[path.Join(top, second)] = true
+				}
+			}
+			for _, name := range slices.Sorted(maps.Keys(unexpected)) {
+				if isInventoryDebris(path.Base(name)) {
+					findings = append(findings, removeFinding(fsys, dir, "E015", name))
+					continue
+				}
+				findings = append(findings, unexpectedFinding("E015", name))
+			}
+		case ocfl.ParseVNum(top, &vnum) == nil:
+			// version directory after the head
+			if slices.Contains(entries[top], path.Join(top, "inventory.json")) {
+				findings = append(findings, &repairFinding{
+					code:    "E001",
+					problem: fmt.Sprintf("version directory after the head version (%s) includes an inventory: %s", rootInv.Head, top),
+				})
+				continue
+			}
+			findings = append(findings, removeFinding(fsys, dir, "E001", top))
+		default:
+			findings = append(findings, unexpectedFinding("E001", top))
+		}
+	}
+	return findings, nil
+}
+
+// latestVersionInventory returns the inventory in the object's last version
+// directory if it is valid and the version is the inventory's head.
+func latestVersionInventory(ctx context.Context, fsys ocflfs.FS, dir string, entries map[string][]string) *ocfl.StoredInventory {
+	var vnums ocfl.VNums
+	for top := range entries {
+		var vnum ocfl.VNum
+		if ocfl.ParseVNum(top, &vnum) == nil {
+			vnums = append(vnums, vnum)
+		}
+	}
+	if len(vnums) == 0 {
+		return nil
+	}
+	last := slices.MaxFunc(vnums, func(a, b ocfl.VNum) int { return a.Num() - b.Num() })
+	inv, err := ocfl.ReadInventory(ctx, fsys, path.Join(dir, last.String()))
+	if err != nil || inv.Head != last {
+		return nil
+	}
+	// the inventory may be corrupt if it doesn't match its sidecar
+	var digestErr *digest.DigestError
+	if err := inv.ValidateSidecar(ctx, fsys, path.Join(dir, last.String())); errors.As(err, &digestErr) {
+		return nil
+	}
+	return inv
+}
+
+// checkSidecar returns a finding if the inventory sidecar in the object's
+// subdirectory invDir doesn't match inv. Fixing an incorrect digest is only
+// safe if it is corroborated by another copy of the inventory (copyInv).
+func checkSidecar(ctx context.Context, fsys ocflfs.FS, dir, invDir string, inv, copyInv *ocfl.StoredInventory) *repairFinding {
+	fullDir := path.Join(dir, invDir)
+	sidecar := path.Join(invDir, "inventory.json."+inv.DigestAlgorithm)
+	fix := func(ctx context.Context) error {
+		return writeSidecar(ctx, fsys, fullDir, inv)
+	}
+	got, err := ocfl.ReadInventorySidecar(ctx, fsys, fullDir, inv.DigestAlgorithm)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return &repairFinding{code: "E058", problem: "inventory sidecar is missing: " + sidecar, fix: fix}
+	case errors.Is(err, ocfl.ErrInventorySidecarContents):
+		return &repairFinding{code: "E061", problem: "inventory sidecar is malformed: " + sidecar, fix: fix}
+	case err != nil:
+		return &repairFinding{problem: fmt.Sprintf("reading inventory sidecar: %s", err)}
+	case strings.EqualFold(got, inv.Digest()):
+		return nil
+	}
+	f := &repairFinding{code: "E060", problem: "inventory sidecar doesn't match the inventory: " + sidecar}
+	if copyInv != nil && copyInv.Digest() == inv.Digest() && copyInv.DigestAlgorithm == inv.DigestAlgorithm {
+		f.fix = fix
+	}
+	return f
+}
+
+// isInventoryDebris returns true if name is a temporary or partially written
+// copy of an inventory or its sidecar, like "inventory.json.tmp".
+func isInventoryDebris(name string) bool {
+	return strings.HasPrefix(name, "inventory.json") && name != "inventory.json"
+}
+
+// removeFinding returns a finding for an orphaned file or directory, name, in
+// the object root.
+func removeFinding(fsys ocflfs.FS, dir string, code string, name string) *repairFinding {
+	return &repairFinding{
+		code:    code,
+		problem: "remove orphaned file(s): " + name,
+		fix: func(ctx context.Context) error {
+			return ocflfs.RemoveAll(ctx, fsys, path.Join(dir, name))
+		},
+	}
+}
+
+// unexpectedFinding returns a finding for a file or directory, name, in the
+// object root that isn't known to be safe to remove.
+func unexpectedFinding(code string, name string) *repairFinding {
+	return &repairFinding{
+		code:    code,
+		problem: "unexpected file(s) must be moved or removed manually: " + name,
+	}
+}
+
+// writeInventory writes inv and its sidecar to dir.
+func writeInventory(ctx context.Context, fsys ocflfs.FS, dir string, inv *ocfl.StoredInventory) error {
+	raw, err := inv.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if _, err := ocflfs.Write(ctx, fsys, path.Join(dir, "inventory.json"), bytes.NewReader(raw)); err != nil {
+		return err
+	}
+	return writeSidecar(ctx, fsys, dir, inv)
+}
+
+// writeSidecar writes the inventory sidecar for inv in dir.
+func writeSidecar(ctx context.Context, fsys ocflfs.FS, dir string, inv *ocfl.StoredInventory) error {
+	name := path.Join(dir, "inventory.json."+inv.DigestAlgorithm)
+	_, err := ocflfs.Write(ctx, fsys, name, strings.NewReader(inv.Digest()+" inventory.json\n"))
+	return err
+}
+
+// validationSummary returns a one-line summary of v with the validation codes
+// of its errors and warnings.
+func validationSummary(v *ocfl.Validation) string {
+	codes := func(errs []error) string {
+		var codes []string
+		for _, err := range errs {
+			var validErr *ocfl.ValidationError
+			if errors.As(err, &validErr) && !slices.Contains(codes, validErr.Code) {
+				codes = append(codes, validErr.Code)
+			}
+		}
+		if len(codes) == 0 {
+			return ""
+		}
+		slices.Sort(codes)
+		return " (" + strings.Join(codes, ", ") + ")"
+	}
+	return fmt.Sprintf("%d error(s)%s, %d warning(s)%s",
+		len(v.Errors()), codes(v.Errors()),
+		len(v.WarnErrors()), codes(v.WarnErrors()))
+}
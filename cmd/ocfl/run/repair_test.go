@@ -0,0 +1,105 @@
+package run_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/testutil"
+)
+
+func TestRepair(t *testing.T) {
+	_, fixtures := testutil.TempDirTestData(t,
+		`testdata/store-fixtures/1.0/good-stores/reg-extension-dir-root`,
+	)
+	root := fixtures[0]
+	id := "ark:123/abc"
+	objDir := filepath.Join(root, "a47", "817", "83d", "cec", "ark%3a123%2fabc")
+
+	t.Run("valid object", func(t *testing.T) {
+		args := []string{`repair`, `--root`, root, `--id`, id}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "no safe repairs found", stdout)
+		})
+	})
+
+	// break the object
+	be.NilErr(t, os.Remove(filepath.Join(objDir, "inventory.json")))
+	be.NilErr(t, os.Remove(filepath.Join(objDir, "inventory.json.sha512")))
+	be.NilErr(t, os.Remove(filepath.Join(objDir, "v1", "inventory.json.sha512")))
+	be.NilErr(t, os.WriteFile(filepath.Join(objDir, "inventory.json.tmp"), []byte("partial"), 0644))
+
+	t.Run("not confirmed", func(t *testing.T) {
+		args := []string{`repair`, `--root`, root, `--id`, id}
+		testutil.RunCLIInput(args, nil, "\n", func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "[safe]", stdout)
+			be.In(t, "object not repaired", stdout)
+		})
+		_, err := os.Stat(filepath.Join(objDir, "inventory.json.tmp"))
+		be.NilErr(t, err)
+	})
+
+	t.Run("repair", func(t *testing.T) {
+		args := []string{`repair`, `--root`, root, `--id`, id, `--yes`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "root inventory is missing: restore from v1", stdout)
+			be.In(t, "inventory sidecar is missing: v1/inventory.json.sha512", stdout)
+			be.In(t, "remove orphaned file(s): inventory.json.tmp", stdout)
+			be.In(t, "after repair:  0 error(s)", stdout)
+		})
+		args = []string{`validate`, `--root`, root, `--id`, id}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+		})
+	})
+
+	t.Run("unsafe", func(t *testing.T) {
+		// the root inventory sidecar doesn't match and there is no version
+		// inventory to corroborate it.
+		be.NilErr(t, os.Remove(filepath.Join(objDir, "v1", "inventory.json")))
+		be.NilErr(t, os.Remove(filepath.Join(objDir, "v1", "inventory.json.sha512")))
+		sidecar := filepath.Join(objDir, "inventory.json.sha512")
+		be.NilErr(t, os.WriteFile(sidecar, []byte("abcd inventory.json\n"), 0644))
+		// files that aren't leftovers from an update aren't removed
+		be.NilErr(t, os.WriteFile(filepath.Join(objDir, "notes.txt"), []byte("notes"), 0644))
+		args := []string{`repair`, `--root`, root, `--id`, id, `--yes`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.Nonzero(t, err)
+			be.In(t, "[unsafe] E060", stdout)
+			be.In(t, "[unsafe] E001 unexpected file(s) must be moved or removed manually: notes.txt", stdout)
+		})
+		_, err := os.Stat(filepath.Join(objDir, "notes.txt"))
+		be.NilErr(t, err)
+	})
+}
+
+func TestRepairCache(t *testing.T) {
+	tmpDir, fixtures := testutil.TempDirTestData(t,
+		`testdata/store-fixtures/1.0/good-stores/reg-extension-dir-root`,
+	)
+	root := fixtures[0]
+	id := "ark:123/abc"
+	contentFile := filepath.Join(root, "a47", "817", "83d", "cec", "ark%3a123%2fabc", "v1", "content", "a_file.txt")
+	testutil.RunCLI([]string{`index`, `build`, `--root`, root}, nil, func(err error, stdout string, stderr string) {
+		be.NilErr(t, err)
+	})
+	srv := httptest.NewServer(http.FileServer(http.Dir(root)))
+	defer srv.Close()
+	env := map[string]string{"OCFL_CACHE": "true", "OCFL_CACHE_DIR": filepath.Join(tmpDir, "cache")}
+	// cache the object's content
+	args := []string{`export`, `--root`, srv.URL, `--id`, id, `--file`, `a_file.txt`, `--to`, `-`}
+	testutil.RunCLI(args, env, func(err error, stdout string, stderr string) {
+		be.NilErr(t, err)
+	})
+	be.NilErr(t, os.WriteFile(contentFile, []byte("corrupted content"), 0644))
+	args = []string{`repair`, `--root`, srv.URL, `--id`, id}
+	testutil.RunCLI(args, env, func(err error, stdout string, stderr string) {
+		be.In(t, "before repair: 1 error(s)", stdout)
+	})
+}
@@ -0,0 +1,249 @@
+package run
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+
+	"github.com/srerickson/ocfl-go"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"golang.org/x/sync/errgroup"
+)
+
+const rootDiffHelp = "Compare the objects in two storage roots for replica consistency"
+
+type RootDiffCmd struct {
+	RootA string `arg:"" name:"root-a" help:"location of the first storage root"`
+	RootB string `arg:"" name:"root-b" help:"location of the second storage root"`
+	JSON  bool   `name:"json" help:"print the comparison as JSON"`
+	Deep  bool   `name:"deep" help:"also compare names and sizes of files in objects with the same inventory"`
+	Jobs  int    `name:"jobs" short:"j" default:"0" help:"number of objects to read concurrently. Defaults to the number of CPU cores."`
+}
+
+// rootDiff is the result of comparing two storage roots
+type rootDiff struct {
+	OnlyA          []string                `json:"only_a"`
+	OnlyB          []string                `json:"only_b"`
+	HeadDiffers    []rootDiffHead          `json:"head_differs"`
+	DigestDiffers  []rootDiffDigest        `json:"digest_differs"`
+	ContentDiffers []rootDiffContent       `json:"content_differs,omitempty"`
+	objectsA       map[string]*ocfl.Object `json:"-"`
+	objectsB       map[string]*ocfl.Object `json:"-"`
+}
+
+type rootDiffHead struct {
+	ID    string `json:"id"`
+	HeadA string `json:"head_a"`
+	HeadB string `json:"head_b"`
+}
+
+type rootDiffDigest struct {
+	ID      string `json:"id"`
+	Head    string `json:"head"`
+	DigestA string `json:"digest_a"`
+	DigestB string `json:"digest_b"`
+}
+
+// rootDiffContent is a file that is missing or has a different size in one of
+// the roots. A size of -1 indicates the file is missing.
+type rootDiffContent struct {
+	ID    string `json:"id"`
+	Path  string `json:"path"`
+	SizeA int64  `json:"size_a"`
+	SizeB int64  `json:"size_b"`
+}
+
+func (cmd *RootDiffCmd) Run(g *globals) error {
+	ctx := g.ctx
+	jobs := cmd.Jobs
+	if jobs < 1 {
+		jobs = runtime.NumCPU()
+	}
+	rootA, err := g.openRoot(cmd.RootA)
+	if err != nil {
+		return err
+	}
+	rootB, err := g.openRoot(cmd.RootB)
+	if err != nil {
+		return err
+	}
+	result := &rootDiff{}
+	if result.objectsA, err = rootObjects(ctx, rootA, jobs); err != nil {
+		return err
+	}
+	if result.objectsB, err = rootObjects(ctx, rootB, jobs); err != nil {
+		return err
+	}
+	result.compare()
+	if cmd.Deep {
+		if err := result.compareContent(ctx, jobs); err != nil {
+			return err
+		}
+	}
+	if cmd.JSON {
+		enc := json.NewEncoder(g.stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			return err
+		}
+	} else {
+		result.print(g)
+	}
+	if !result.Empty() {
+		return errors.New("storage roots are not consistent")
+	}
+	return nil
+}
+
+// rootObjects returns all objects in root, indexed by ID.
+func rootObjects(ctx context.Context, root *ocfl.Root, jobs int) (map[string]*ocfl.Object, error) {
+	objects := map[string]*ocfl.Object{}
+	for obj, err := range root.ObjectsBatch(ctx, jobs) {
+		if err != nil {
+			rootLoc := locationString(root.FS(), root.Path())
+			return nil, fmt.Errorf("while listing objects in %s: %w", rootLoc, err)
+		}
+		objects[obj.ID()] = obj
+	}
+	return objects, nil
+}
+
+func (d *rootDiff) compare() {
+	d.OnlyA, d.OnlyB = []string{}, []string{}
+	d.HeadDiffers, d.DigestDiffers = []rootDiffHead{}, []rootDiffDigest{}
+	for id, objA := range d.objectsA {
+		objB := d.objectsB[id]
+		switch {
+		case objB == nil:
+			d.OnlyA = append(d.OnlyA, id)
+		case objA.Head() != objB.Head():
+			d.HeadDiffers = append(d.HeadDiffers, rootDiffHead{
+				ID:    id,
+				HeadA: objA.Head().String(),
+				HeadB: objB.Head().String(),
+			})
+		case objA.InventoryDigest() != objB.InventoryDigest():
+			d.DigestDiffers = append(d.DigestDiffers, rootDiffDigest{
+				ID:      id,
+				Head:    objA.Head().String(),
+				DigestA: objA.InventoryDigest(),
+				DigestB: objB.InventoryDigest(),
+			})
+		}
+	}
+	for id := range d.objectsB {
+		if d.objectsA[id] == nil {
+			d.OnlyB = append(d.OnlyB, id)
+		}
+	}
+	slices.Sort(d.OnlyA)
+	slices.Sort(d.OnlyB)
+	slices.SortFunc(d.HeadDiffers, func(a, b rootDiffHead) int { return cmp.Compare(a.ID, b.ID) })
+	slices.SortFunc(d.DigestDiffers, func(a, b rootDiffDigest) int { return cmp.Compare(a.ID, b.ID) })
+}
+
+// compareContent compares file names and sizes for objects that exist in both
+// roots with the same inventory.
+func (d *rootDiff) compareContent(ctx context.Context, jobs int) error {
+	var ids []string
+	for id, objA := range d.objectsA {
+		objB := d.objectsB[id]
+		if objB != nil && objA.InventoryDigest() == objB.InventoryDigest() {
+			ids = append(ids, id)
+		}
+	}
+	results := make([][]rootDiffContent, len(ids))
+	grp, ctx := errgroup.WithContext(ctx)
+	grp.SetLimit(jobs)
+	for i, id := range ids {
+		grp.Go(func() error {
+			sizesA, err := objectFileSizes(ctx, d.objectsA[id])
+			if err != nil {
+				return err
+			}
+			sizesB, err := objectFileSizes(ctx, d.objectsB[id])
+			if err != nil {
+				return err
+			}
+			for name, sizeA := range sizesA {
+				sizeB, ok := sizesB[name]
+				if !ok {
+					sizeB = -1
+				}
+				if sizeA != sizeB {
+					results[i] = append(results[i], rootDiffContent{ID: id, Path: name, SizeA: sizeA, SizeB: sizeB})
+				}
+			}
+			for name, sizeB := range sizesB {
+				if _, ok := sizesA[name]; !ok {
+					results[i] = append(results[i], rootDiffContent{ID: id, Path: name, SizeA: -1, SizeB: sizeB})
+				}
+			}
+			return nil
+		})
+	}
+	if err := grp.Wait(); err != nil {
+		return err
+	}
+	d.ContentDiffers = slices.Concat(results...)
+	slices.SortFunc(d.ContentDiffers, func(a, b rootDiffContent) int {
+		return cmp.Or(cmp.Compare(a.ID, b.ID), cmp.Compare(a.Path, b.Path))
+	})
+	return nil
+}
+
+// objectFileSizes returns the sizes of all files in the object directory,
+// indexed by their path relative to the object root.
+func objectFileSizes(ctx context.Context, obj *ocfl.Object) (map[string]int64, error) {
+	sizes := map[string]int64{}
+	for file, err := range ocflfs.WalkFiles(ctx, obj.FS(), obj.Path()) {
+		if err != nil {
+			return nil, err
+		}
+		if file.Info == nil {
+			if err := file.Stat(ctx); err != nil {
+				return nil, err
+			}
+		}
+		sizes[file.Path] = file.Info.Size()
+	}
+	return sizes, nil
+}
+
+// Empty returns true if no differences were found.
+func (d rootDiff) Empty() bool {
+	return len(d.OnlyA) == 0 &&
+		len(d.OnlyB) == 0 &&
+		len(d.HeadDiffers) == 0 &&
+		len(d.DigestDiffers) == 0 &&
+		len(d.ContentDiffers) == 0
+}
+
+func (d rootDiff) print(g *globals) {
+	for _, id := range d.OnlyA {
+		fmt.Fprintln(g.stdout, "only in A:", id)
+	}
+	for _, id := range d.OnlyB {
+		fmt.Fprintln(g.stdout, "only in B:", id)
+	}
+	for _, h := range d.HeadDiffers {
+		fmt.Fprintf(g.stdout, "head differs: %s (A: %s, B: %s)\n", h.ID, h.HeadA, h.HeadB)
+	}
+	for _, dig := range d.DigestDiffers {
+		fmt.Fprintf(g.stdout, "inventory differs: %s %s (A: %s, B: %s)\n", dig.ID, dig.Head, dig.DigestA, dig.DigestB)
+	}
+	sizeStr := func(s int64) string {
+		if s < 0 {
+			return "missing"
+		}
+		return fmt.Sprintf("%d bytes", s)
+	}
+	for _, c := range d.ContentDiffers {
+		fmt.Fprintf(g.stdout, "content differs: %s %s (A: %s, B: %s)\n", c.ID, c.Path, sizeStr(c.SizeA), sizeStr(c.SizeB))
+	}
+	fmt.Fprintf(g.stdout, "compared %d object(s) in A and %d object(s) in B\n", len(d.objectsA), len(d.objectsB))
+}
@@ -0,0 +1,92 @@
+package run_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/testutil"
+)
+
+func TestRootDiff(t *testing.T) {
+	tmpDir, fixtures := testutil.TempDirTestData(t,
+		`testdata/store-fixtures/1.0/good-stores/reg-extension-dir-root`,
+		`testdata/content-fixture`,
+	)
+	rootA := fixtures[0]
+	contentFixture := fixtures[1]
+	rootB := filepath.Join(tmpDir, "replica")
+	id := "ark:123/abc"
+	testutil.RunCLI([]string{`sync`, `--from`, rootA, `--to`, rootB}, nil, func(err error, stdout string, stderr string) {
+		be.NilErr(t, err)
+	})
+
+	t.Run("consistent", func(t *testing.T) {
+		args := []string{`root-diff`, rootA, rootB, `--deep`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "compared 1 object(s) in A and 1 object(s) in B", stdout)
+		})
+	})
+
+	t.Run("content size", func(t *testing.T) {
+		// a content file in the replica is truncated
+		var contentFile string
+		filepath.WalkDir(rootB, func(name string, d os.DirEntry, err error) error {
+			if filepath.Base(name) == "a_file.txt" {
+				contentFile = name
+			}
+			return err
+		})
+		be.NilErr(t, os.WriteFile(contentFile, nil, 0644))
+		args := []string{`root-diff`, rootA, rootB}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+		})
+		args = []string{`root-diff`, rootA, rootB, `--deep`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.Nonzero(t, err)
+			be.In(t, "content differs: ark:123/abc v1/content/a_file.txt", stdout)
+		})
+	})
+
+	t.Run("head differs", func(t *testing.T) {
+		args := []string{`commit`, `--root`, rootA, `--id`, id, `-m`, "update", `-n`, "Tester", contentFixture}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+		})
+		args = []string{`root-diff`, rootA, rootB, `--json`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.Nonzero(t, err)
+			be.In(t, "not consistent", stderr)
+			var result struct {
+				OnlyA       []string `json:"only_a"`
+				HeadDiffers []struct {
+					ID    string `json:"id"`
+					HeadA string `json:"head_a"`
+					HeadB string `json:"head_b"`
+				} `json:"head_differs"`
+			}
+			be.NilErr(t, json.Unmarshal([]byte(stdout), &result))
+			be.Equal(t, 0, len(result.OnlyA))
+			be.Equal(t, 1, len(result.HeadDiffers))
+			be.Equal(t, id, result.HeadDiffers[0].ID)
+			be.Equal(t, "v2", result.HeadDiffers[0].HeadA)
+			be.Equal(t, "v1", result.HeadDiffers[0].HeadB)
+		})
+	})
+
+	t.Run("only in one root", func(t *testing.T) {
+		args := []string{`delete`, `--root`, rootB, `--id`, id, `--yes`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+		})
+		args = []string{`root-diff`, rootA, rootB}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.Nonzero(t, err)
+			be.In(t, "only in A: "+id, stdout)
+		})
+	})
+}
@@ -1,16 +1,23 @@
 package run
 
 import (
+	"cmp"
 	"context"
 	_ "embed"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log/slog"
+	"maps"
+	"net/http"
 	"net/url"
 	"path"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/alecthomas/kong"
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -20,9 +27,13 @@ import (
 	"github.com/charmbracelet/log"
 	"github.com/srerickson/ocfl-go"
 	ocflfs "github.com/srerickson/ocfl-go/fs"
+	ocflhttp "github.com/srerickson/ocfl-go/fs/http"
 	"github.com/srerickson/ocfl-go/fs/local"
 	ocflS3 "github.com/srerickson/ocfl-go/fs/s3"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/archivefs"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/cache"
 	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/httpfs"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/memfs"
 )
 
 const (
@@ -33,6 +44,19 @@ const (
 	// if "true", enable path-style addressing for s3
 	envVarS3PathStyle = "OCFL_S3_PATHSTYLE"
 
+	// settings for http(s) locations
+	envVarHTTPToken    = "OCFL_HTTP_TOKEN"     // bearer token
+	envVarHTTPUsername = "OCFL_HTTP_USERNAME"  // basic auth username
+	envVarHTTPPassword = "OCFL_HTTP_PASSWORD"  // basic auth password
+	envVarHTTPCAFile   = "OCFL_HTTP_CA_FILE"   // PEM file with CA certificates
+	envVarHTTPCertFile = "OCFL_HTTP_CERT_FILE" // PEM file with client certificate
+	envVarHTTPKeyFile  = "OCFL_HTTP_KEY_FILE"  // PEM file with client key
+	envVarHTTPTimeout  = "OCFL_HTTP_TIMEOUT"   // response timeout (e.g., "30s")
+	envVarHTTPRetries  = "OCFL_HTTP_RETRIES"   // retries for temporary errors
+	envVarNetrc        = "NETRC"               // .netrc file path
+
+	defaultHTTPRetries = 2
+
 	// keys that can be used in tests
 	envVarAWSKey      = "AWS_ACCESS_KEY_ID"
 	envVarAWSSecret   = "AWS_SECRET_ACCESS_KEY"
@@ -45,16 +69,29 @@ func CLI(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.
 		kong.Writers(stdout, stderr),
 		kong.Description("command line tool for working with OCFL repositories"),
 		kong.Vars{
+			"audit_help":     auditHelp,
+			"cache_help":     cacheHelp,
 			"commit_help":    commitHelp,
 			"diff_help":      diffHelp,
 			"delete_help":    deleteHelp,
 			"export_help":    exportHelp,
+			"gc_help":        gcHelp,
+			"history_help":   historyHelp,
+			"index_help":     indexHelp,
 			"info_help":      infoHelp,
 			"init_root_help": initRootHelp,
 			"ls_help":        lsHelp,
 			"log_help":       logHelp,
+			"pack_help":      packHelp,
+			"repair_help":    repairHelp,
+			"root_diff_help": rootDiffHelp,
+			"serve_help":     serveHelp,
 			"stage_help":     stageHelp,
+			"stats_help":     statsHelp,
+			"sync_help":      syncHelp,
+			"unpack_help":    unpackHelp,
 			"validate_help":  validateHelp,
+			"webdav_help":    webdavHelp,
 			"env_root":       envVarRoot,
 			"env_user_name":  envVarUserName,
 			"env_user_email": envVarUserEmail,
@@ -82,14 +119,26 @@ func CLI(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.
 	cli.globals.stderr = stderr
 	cli.globals.stdin = stdin
 	cli.globals.getenv = getenv
+	cli.globals.noCache = false
+	cli.globals.closers = nil
+	defer func() {
+		for _, c := range cli.globals.closers {
+			c.Close()
+		}
+	}()
 	logLevel := log.InfoLevel
 	if cli.Debug {
 		logLevel = log.DebugLevel
 	}
 	cli.globals.logger = newLogger(logLevel, stderr)
-	//root config from flag or environment
+	cli.globals.config, err = readConfig(configPath(getenv))
+	if err != nil {
+		cli.globals.logger.Error(err.Error())
+		return err
+	}
+	//root config from flag, environment, or config file
 	if cli.globals.RootLocation == "" {
-		cli.globals.RootLocation = getenv(envVarRoot)
+		cli.globals.RootLocation = cmp.Or(getenv(envVarRoot), cli.globals.config.Root)
 	}
 	if err := kongCtx.Run(&cli.globals); err != nil {
 		cli.globals.logger.Error(err.Error())
@@ -100,17 +149,30 @@ func CLI(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.
 
 var cli struct {
 	globals
+	Audit    AuditCmd    `cmd:"" help:"${audit_help}"`
+	Cache    CacheCmd    `cmd:"" help:"${cache_help}"`
 	Commit   CommitCmd   `cmd:"" help:"${commit_help}"`
 	Diff     DiffCmd     `cmd:"" help:"${diff_help}"`
 	Delete   DeleteCmd   `cmd:"" help:"${delete_help}"`
 	Export   ExportCmd   `cmd:"" help:"${export_help}"`
+	GC       GCCmd       `cmd:"" help:"${gc_help}"`
+	History  HistoryCmd  `cmd:"" help:"${history_help}"`
+	Index    IndexCmd    `cmd:"" help:"${index_help}"`
 	Info     InfoCmd     `cmd:"" help:"${info_help}"`
 	InitRoot InitRootCmd `cmd:"" help:"${init_root_help}"`
 	Log      LogCmd      `cmd:"" help:"${log_help}"`
 	Ls       LsCmd       `cmd:"" help:"${ls_help}"`
+	Pack     PackCmd     `cmd:"" help:"${pack_help}"`
+	Repair   RepairCmd   `cmd:"" help:"${repair_help}"`
+	RootDiff RootDiffCmd `cmd:"" help:"${root_diff_help}"`
+	Serve    ServeCmd    `cmd:"" help:"${serve_help}"`
 	Stage    StageCmd    `cmd:"" help:"${stage_help}"`
+	Stats    StatsCmd    `cmd:"" help:"${stats_help}"`
+	Sync     SyncCmd     `cmd:"" help:"${sync_help}"`
+	Unpack   UnpackCmd   `cmd:"" help:"${unpack_help}"`
 	Validate ValidateCmd `cmd:"" help:"${validate_help}"`
 	Version  VersionCmd  `cmd:"" help:"Print ocfl-tools version information"`
+	WebDAV   WebDAVCmd   `cmd:"" name:"webdav" help:"${webdav_help}"`
 }
 
 type globals struct {
@@ -120,17 +182,44 @@ type globals struct {
 	stdin  io.Reader
 	getenv func(string) string
 	logger *slog.Logger
+	config *configFile
+	// noCache is set by commands that must read remote storage roots
+	// directly, without the local cache.
+	noCache bool
+	// closers are closed when the command finishes
+	closers []io.Closer
 
-	RootLocation string `name:"root" help:"The prefix/directory of the OCFL storage root used for the command ($$${env_root})"`
+	RootLocation string `name:"root" help:"The prefix/directory of the OCFL storage root used for the command, or @name for a storage root in the config file ($$${env_root})"`
 	Debug        bool   `name:"debug" help:"enable debug log messages"`
 }
 
-// convert a location, which may be a local path or an 's3://' path, into
-// an FS and a path.
+// convert a location, which may be a local path, an 's3://' path, or the
+// name of a storage root in the config file ('@name'), into an FS and a path.
 func (g *globals) parseLocation(loc string) (ocflfs.FS, string, error) {
 	if loc == "" {
 		return nil, "", errors.New("location not set")
 	}
+	var rootConf *rootConfig
+	if name, ok := strings.CutPrefix(loc, rootNamePrefix); ok {
+		var err error
+		if rootConf, err = g.config.namedRoot(name); err != nil {
+			return nil, "", err
+		}
+		loc = rootConf.Location
+	}
+	// zip, tar, and mem locations aren't parsed as URLs: zip and tar locations
+	// include a local file path.
+	switch scheme, rest, _ := strings.Cut(loc, "://"); scheme {
+	case "zip", "tar":
+		return g.openArchive(scheme, rest)
+	case "mem":
+		name, prefix, _ := strings.Cut(rest, "/")
+		prefix, err := locationPrefix(prefix)
+		if err != nil {
+			return nil, "", fmt.Errorf("in location %q: %w", loc, err)
+		}
+		return memfs.Named(name), prefix, nil
+	}
 	locUrl, err := url.Parse(loc)
 	if err != nil {
 		return nil, "", err
@@ -144,21 +233,42 @@ func (g *globals) parseLocation(loc string) (ocflfs.FS, string, error) {
 		// values passed through getenv are mostly for testing.
 		envKey := g.getenv(envVarAWSKey)
 		envSecret := g.getenv(envVarAWSSecret)
-		envRegion := g.getenv(envVarAWSRegion)
-		envEndpoint := g.getenv(envVarAWSEndpoint)
-		if envKey != "" && envSecret != "" {
+		settings := s3Settings{
+			region:    g.getenv(envVarAWSRegion),
+			endpoint:  g.getenv(envVarAWSEndpoint),
+			pathStyle: strings.EqualFold(g.getenv(envVarS3PathStyle), "true"),
+		}
+		// settings for named roots take precedence over the environment, and
+		// settings in the location's query take precedence over both.
+		if rootConf != nil {
+			settings.region = cmp.Or(rootConf.Region, settings.region)
+			settings.endpoint = cmp.Or(rootConf.Endpoint, settings.endpoint)
+			settings.profile = rootConf.Profile
+			if rootConf.PathStyle != nil {
+				settings.pathStyle = *rootConf.PathStyle
+			}
+		}
+		if err := settings.setQuery(locUrl.Query()); err != nil {
+			return nil, "", fmt.Errorf("in location %q: %w", loc, err)
+		}
+		// static credentials from the environment would take precedence over
+		// the profile's credentials.
+		if envKey != "" && envSecret != "" && settings.profile == "" {
 			creds := credentials.NewStaticCredentialsProvider(envKey, envSecret, "")
 			awsOpts = append(awsOpts, config.WithCredentialsProvider(creds))
 		}
-		if envRegion != "" {
-			awsOpts = append(awsOpts, config.WithRegion(envRegion))
+		if settings.profile != "" {
+			awsOpts = append(awsOpts, config.WithSharedConfigProfile(settings.profile))
 		}
-		if envEndpoint != "" {
+		if settings.region != "" {
+			awsOpts = append(awsOpts, config.WithRegion(settings.region))
+		}
+		if settings.endpoint != "" {
 			s3Opts = append(s3Opts, func(o *s3.Options) {
-				o.BaseEndpoint = aws.String(envEndpoint)
+				o.BaseEndpoint = aws.String(settings.endpoint)
 			})
 		}
-		if strings.EqualFold(g.getenv(envVarS3PathStyle), "true") {
+		if settings.pathStyle {
 			s3Opts = append(s3Opts, func(o *s3.Options) {
 				o.UsePathStyle = true
 			})
@@ -168,10 +278,25 @@ func (g *globals) parseLocation(loc string) (ocflfs.FS, string, error) {
 			return nil, "", err
 		}
 		s3Client := s3.NewFromConfig(cfg, s3Opts...)
-		fsys := ocflS3.NewBucketFS(s3Client, bucket, ocflS3.WithLogger(g.logger))
+		fsys, err := g.withCache(ocflS3.NewBucketFS(s3Client, bucket, ocflS3.WithLogger(g.logger)),
+			"s3://"+bucket+"?endpoint="+url.QueryEscape(settings.endpoint), rootConf)
+		if err != nil {
+			return nil, "", err
+		}
 		return fsys, prefix, nil
 	case "http", "https":
-		fsys := httpfs.New(loc)
+		clientConf, err := g.httpClientConfig(rootConf)
+		if err != nil {
+			return nil, "", err
+		}
+		client, err := httpfs.NewClient(loc, clientConf)
+		if err != nil {
+			return nil, "", err
+		}
+		fsys, err := g.withCache(httpfs.New(loc, ocflhttp.WithClient(client)), loc, rootConf)
+		if err != nil {
+			return nil, "", err
+		}
 		return fsys, ".", nil
 	default:
 		absPath, err := filepath.Abs(loc)
@@ -186,8 +311,149 @@ func (g *globals) parseLocation(loc string) (ocflfs.FS, string, error) {
 	}
 }
 
+// openArchive returns a read-only FS for the zip or tar file in the location,
+// which has the form 'path/to/file.zip!/prefix'.
+func (g *globals) openArchive(format string, loc string) (ocflfs.FS, string, error) {
+	name, prefix, _ := strings.Cut(loc, "!")
+	if name == "" {
+		return nil, "", fmt.Errorf("%s location is missing a file name", format)
+	}
+	prefix, err := locationPrefix(prefix)
+	if err != nil {
+		return nil, "", fmt.Errorf("in %s location %q: %w", format, loc, err)
+	}
+	fsys, err := openArchiveFile(format, name)
+	if err != nil {
+		return nil, "", err
+	}
+	g.closers = append(g.closers, fsys)
+	return fsys, prefix, nil
+}
+
+// openArchiveFile opens the zip or tar file name as a read-only FS.
+func openArchiveFile(format string, name string) (*archivefs.FS, error) {
+	if format == "zip" {
+		return archivefs.OpenZip(name)
+	}
+	return archivefs.OpenTar(name)
+}
+
+// locationPrefix returns the prefix (directory) in zip, tar, and mem
+// locations as a valid fs path.
+func locationPrefix(prefix string) (string, error) {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return ".", nil
+	}
+	if !fs.ValidPath(prefix) {
+		return "", fmt.Errorf("invalid path: %q", prefix)
+	}
+	return prefix, nil
+}
+
+// s3Settings are S3 client settings from the environment, the config file, or
+// the location's query string.
+type s3Settings struct {
+	endpoint  string
+	region    string
+	profile   string
+	pathStyle bool
+}
+
+// s3QueryParams are the query parameters supported in 's3://' locations.
+var s3QueryParams = []string{"endpoint", "region", "profile", "path_style"}
+
+// setQuery sets values from an 's3://' location's query parameters.
+func (s *s3Settings) setQuery(query url.Values) error {
+	for _, key := range slices.Sorted(maps.Keys(query)) {
+		vals := query[key]
+		val := vals[len(vals)-1]
+		switch key {
+		case "endpoint":
+			s.endpoint = val
+		case "region":
+			s.region = val
+		case "profile":
+			s.profile = val
+		case "path_style":
+			pathStyle, err := strconv.ParseBool(val)
+			if err != nil {
+				return fmt.Errorf("invalid path_style value %q: must be true or false", val)
+			}
+			s.pathStyle = pathStyle
+		default:
+			return fmt.Errorf("unknown s3 parameter %q (supported: %s)", key, strings.Join(s3QueryParams, ", "))
+		}
+	}
+	return nil
+}
+
+// httpClientConfig returns the client config for http(s) locations from the
+// environment and the named root's settings (which take precedence).
+func (g *globals) httpClientConfig(rootConf *rootConfig) (httpfs.ClientConfig, error) {
+	if rootConf == nil {
+		rootConf = &rootConfig{}
+	}
+	conf := httpfs.ClientConfig{
+		Token:    cmp.Or(rootConf.Token, g.getenv(envVarHTTPToken)),
+		Username: cmp.Or(rootConf.Username, g.getenv(envVarHTTPUsername)),
+		Password: cmp.Or(rootConf.Password, g.getenv(envVarHTTPPassword)),
+		CAFile:   cmp.Or(rootConf.CAFile, g.getenv(envVarHTTPCAFile)),
+		CertFile: cmp.Or(rootConf.CertFile, g.getenv(envVarHTTPCertFile)),
+		KeyFile:  cmp.Or(rootConf.KeyFile, g.getenv(envVarHTTPKeyFile)),
+		Timeout:  rootConf.Timeout.Duration,
+		Retries:  defaultHTTPRetries,
+	}
+	if len(rootConf.Headers) > 0 {
+		conf.Header = http.Header{}
+		for k, v := range rootConf.Headers {
+			conf.Header.Set(k, v)
+		}
+	}
+	if conf.Timeout == 0 {
+		if val := g.getenv(envVarHTTPTimeout); val != "" {
+			timeout, err := time.ParseDuration(val)
+			if err != nil {
+				return conf, fmt.Errorf("invalid %s: %w", envVarHTTPTimeout, err)
+			}
+			conf.Timeout = timeout
+		}
+	}
+	switch {
+	case rootConf.Retries != nil:
+		conf.Retries = *rootConf.Retries
+	case g.getenv(envVarHTTPRetries) != "":
+		retries, err := strconv.Atoi(g.getenv(envVarHTTPRetries))
+		if err != nil || retries < 0 {
+			return conf, fmt.Errorf("invalid %s: must be a non-negative integer", envVarHTTPRetries)
+		}
+		conf.Retries = retries
+	}
+	conf.NetrcFile = g.getenv(envVarNetrc)
+	if conf.NetrcFile == "" && g.getenv(envVarHome) != "" {
+		conf.NetrcFile = filepath.Join(g.getenv(envVarHome), ".netrc")
+	}
+	return conf, nil
+}
+
 func (g *globals) getRoot() (*ocfl.Root, error) {
-	fsys, dir, err := g.parseLocation(g.RootLocation)
+	return g.openRoot(g.RootLocation)
+}
+
+// rootConfig returns the config file settings for the active storage root if
+// it is a named root. Otherwise, it returns an empty config.
+func (g *globals) rootConfig() *rootConfig {
+	if name, ok := strings.CutPrefix(g.RootLocation, rootNamePrefix); ok && g.config != nil {
+		if conf := g.config.Roots[name]; conf != nil {
+			return conf
+		}
+	}
+	return &rootConfig{}
+}
+
+// openRoot returns the storage root at the location loc.
+func (g *globals) openRoot(loc string) (*ocfl.Root, error) {
+	fsys, dir, err := g.parseLocation(loc)
 	if err != nil {
 		return nil, err
 	}
@@ -230,6 +496,17 @@ func (g *globals) newObject(id, objPath string, opts ...ocfl.ObjectOption) (*ocf
 
 func locationString(fsys ocflfs.FS, dir string) string {
 	switch fsys := fsys.(type) {
+	case *cache.FS:
+		return locationString(fsys.Unwrap(), dir)
+	case *cache.WriteFS:
+		return locationString(fsys.Unwrap(), dir)
+	case *archivefs.FS:
+		if dir == "." {
+			dir = ""
+		}
+		return fsys.Format() + "://" + fsys.Name() + "!/" + dir
+	case *memfs.FS:
+		return "mem://" + path.Join(fsys.Name(), dir)
 	case *httpfs.FS:
 		base := fsys.URL()
 		if dir == "." {
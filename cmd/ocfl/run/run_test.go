@@ -1,6 +1,9 @@
 package run_test
 
 import (
+	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/carlmjohnson/be"
@@ -18,3 +21,122 @@ func TestRootNotSet(t *testing.T) {
 		})
 	}
 }
+
+func TestS3LocationQuery(t *testing.T) {
+	t.Run("unknown parameter", func(t *testing.T) {
+		args := []string{"ls", "--root", "s3://bucket/prefix?endpoint=http://localhost:9000&regoin=us-east-1"}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.True(t, err != nil)
+			be.In(t, `unknown s3 parameter "regoin"`, stderr)
+		})
+	})
+	t.Run("invalid path_style", func(t *testing.T) {
+		args := []string{"ls", "--root", "s3://bucket/prefix?path_style=maybe"}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.True(t, err != nil)
+			be.In(t, `invalid path_style value "maybe"`, stderr)
+		})
+	})
+	// connecting requires S3 tests to be enabled
+	if testutil.S3Enabled() {
+		t.Run("with endpoint", func(t *testing.T) {
+			loc := testutil.TempS3Location(t, "root") + "?endpoint=" + url.QueryEscape(testutil.S3Endpoint()) + "&path_style=true"
+			testutil.RunCLI([]string{"init-root", "--root", loc}, nil, func(err error, stdout string, stderr string) {
+				be.NilErr(t, err)
+			})
+			testutil.RunCLI([]string{"ls", "--root", loc}, nil, func(err error, stdout string, stderr string) {
+				be.NilErr(t, err)
+			})
+		})
+	}
+}
+
+func TestArchiveLocation(t *testing.T) {
+	tmp, fixtures := testutil.TempDirTestData(t,
+		"testdata/store-fixtures/1.0/good-stores/reg-extension-dir-root",
+		"testdata/object-fixtures/1.1/good-objects/spec-ex-full",
+	)
+	rootFixture := fixtures[0]
+	objFixture := fixtures[1]
+	for _, format := range []string{"zip", "tar"} {
+		t.Run(format, func(t *testing.T) {
+			rootArchive := filepath.Join(tmp, "root."+format)
+			objArchive := filepath.Join(tmp, "object."+format)
+			testutil.ArchiveDir(t, rootFixture, rootArchive, "root")
+			testutil.ArchiveDir(t, objFixture, objArchive, "")
+			rootLoc := format + "://" + rootArchive + "!/root"
+			objLoc := format + "://" + objArchive
+			t.Run("ls root", func(t *testing.T) {
+				testutil.RunCLI([]string{"ls", "--root", rootLoc}, nil, func(err error, stdout string, stderr string) {
+					be.NilErr(t, err)
+					be.In(t, "ark:123/abc", stdout)
+				})
+			})
+			t.Run("validate root", func(t *testing.T) {
+				testutil.RunCLI([]string{"validate", "--root", rootLoc}, nil, func(err error, stdout string, stderr string) {
+					be.NilErr(t, err)
+				})
+			})
+			t.Run("export", func(t *testing.T) {
+				args := []string{"export", "--root", rootLoc, "--id", "ark:123/abc", "--file", "a_file.txt", "--to", "-"}
+				testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+					be.NilErr(t, err)
+					expect, err := os.ReadFile(filepath.Join(rootFixture, "a47", "817", "83d", "cec", "ark%3a123%2fabc", "v1", "content", "a_file.txt"))
+					be.NilErr(t, err)
+					be.Equal(t, string(expect), stdout)
+				})
+			})
+			t.Run("object", func(t *testing.T) {
+				testutil.RunCLI([]string{"ls", "--object", objLoc}, nil, func(err error, stdout string, stderr string) {
+					be.NilErr(t, err)
+					be.In(t, "image.tiff", stdout)
+				})
+				testutil.RunCLI([]string{"validate", "--object", objLoc}, nil, func(err error, stdout string, stderr string) {
+					be.NilErr(t, err)
+				})
+			})
+			t.Run("read-only", func(t *testing.T) {
+				args := []string{"commit", "--root", rootLoc, "--id", "new-object", "-m", "new", objFixture}
+				testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+					be.True(t, err != nil)
+				})
+			})
+		})
+	}
+	t.Run("hidden directory", func(t *testing.T) {
+		archive := filepath.Join(tmp, "hidden.zip")
+		testutil.ArchiveDir(t, rootFixture, archive, ".hidden/root")
+		loc := "zip://" + archive + "!/.hidden/missing"
+		testutil.RunCLI([]string{"ls", "--root", loc}, nil, func(err error, stdout string, stderr string) {
+			be.True(t, err != nil)
+			be.In(t, loc, stderr)
+		})
+	})
+	t.Run("missing file", func(t *testing.T) {
+		args := []string{"ls", "--root", "zip://" + filepath.Join(tmp, "missing.zip") + "!/root"}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.True(t, err != nil)
+		})
+	})
+}
+
+func TestMemLocation(t *testing.T) {
+	_, fixtures := testutil.TempDirTestData(t, "testdata/content-fixture")
+	contentFixture := fixtures[0]
+	root := "mem://" + t.Name() + "/root"
+	testutil.RunCLI([]string{"init-root", "--root", root}, nil, func(err error, stdout string, stderr string) {
+		be.NilErr(t, err)
+		be.In(t, root, stdout)
+	})
+	args := []string{"commit", "--root", root, "--id", "object-01", "-m", "first", "-n", "Me", "-e", "me@domain.net", contentFixture}
+	testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+		be.NilErr(t, err)
+	})
+	testutil.RunCLI([]string{"ls", "--root", root}, nil, func(err error, stdout string, stderr string) {
+		be.NilErr(t, err)
+		be.In(t, "object-01", stdout)
+	})
+	testutil.RunCLI([]string{"validate", "--root", root}, nil, func(err error, stdout string, stderr string) {
+		be.NilErr(t, err)
+	})
+}
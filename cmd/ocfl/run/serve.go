@@ -0,0 +1,112 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/srerickson/ocfl-go"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/server"
+)
+
+const serveHelp = "Serve a web interface for browsing, downloading, and (optionally) updating objects in the storage root"
+
+type ServeCmd struct {
+	Addr      string `name:"addr" default:":8080" help:"address to listen on"`
+	Tokens    string `name:"tokens" help:"file with access tokens and the object IDs they can update. If set, endpoints for updating objects are enabled."`
+	StageDir  string `name:"stage-dir" help:"directory for stages and uploaded files. Defaults to a temporary directory that is removed when the server stops."`
+	MaxUpload string `name:"max-upload-size" default:"10GiB" help:"maximum size of upload requests (e.g., 500MB, 10GiB). Use 0 for no limit."`
+}
+
+func (cmd *ServeCmd) Run(g *globals) error {
+	root, err := g.getRoot()
+	if err != nil {
+		return err
+	}
+	opts := []server.Option{server.WithLogger(g.logger)}
+	if cmd.Tokens != "" {
+		writeOpt, cleanup, err := cmd.writeOption(g)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		maxUpload, err := parseByteSize(cmd.MaxUpload)
+		if err != nil {
+			return fmt.Errorf("in --max-upload-size: %w", err)
+		}
+		opts = append(opts, writeOpt, server.WithMaxUploadSize(maxUpload))
+	}
+	g.logger.Info("serving storage root", "root", locationString(root.FS(), root.Path()))
+	return listenAndServe(g, cmd.Addr, server.New(root, opts...))
+}
+
+// listenAndServe serves HTTP requests with the handler until the command is
+// interrupted.
+func listenAndServe(g *globals, addr string, handler http.Handler) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(g.ctx, os.Interrupt)
+	defer stop()
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	g.logger.Info("listening", "addr", ln.Addr().String())
+	errs := make(chan error, 1)
+	go func() { errs <- srv.Serve(ln) }()
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return nil
+}
+
+// writeOption returns the server option that enables the write API and a
+// function to remove the stage directory if it's temporary.
+func (cmd *ServeCmd) writeOption(g *globals) (server.Option, func(), error) {
+	tokens, err := server.ReadTokens(cmd.Tokens)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading access tokens: %w", err)
+	}
+	cleanup := func() {}
+	stageDir := cmd.StageDir
+	if stageDir == "" {
+		if stageDir, err = os.MkdirTemp("", "ocfl-stages-*"); err != nil {
+			return nil, nil, err
+		}
+		cleanup = func() { os.RemoveAll(stageDir) }
+	}
+	if stageDir, err = filepath.Abs(stageDir); err != nil {
+		return nil, nil, err
+	}
+	if err := os.MkdirAll(stageDir, 0755); err != nil {
+		return nil, nil, err
+	}
+	commit := func(ctx context.Context, obj *ocfl.Object, stage *ocfl.Stage, msg string, user ocfl.User) error {
+		updated, err := objectUpdateOrRevert(ctx, obj, stage, msg, user, g.logger)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			return errors.New("object update was interrupted")
+		}
+		return nil
+	}
+	g.logger.Info("write API enabled", "stage_dir", stageDir)
+	return server.WithWrites(stageDir, tokens, commit), cleanup, nil
+}
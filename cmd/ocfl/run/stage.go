@@ -1,6 +1,7 @@
 package run
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
@@ -8,6 +9,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/srerickson/ocfl-go"
@@ -40,7 +42,7 @@ type stageCmdBase struct {
 // stage new
 type NewStageCmd struct {
 	stageCmdBase
-	Alg string `name:"alg" default:"sha512" help:"Digest Algorithm used to digest content. Ignored for existing objects."`
+	Alg string `name:"alg" help:"Digest Algorithm used to digest content. Ignored for existing objects. Defaults to the storage root's digest_algorithm setting or sha512."`
 	ID  string `name:"id" short:"i" required:"" help:"object id for the new stage"`
 }
 
@@ -57,7 +59,7 @@ func (cmd *NewStageCmd) Run(g *globals) error {
 	if err != nil {
 		return err
 	}
-	stage, err := stage.NewStageFile(obj, cmd.Alg)
+	stage, err := g.newStageFile(obj, cmd.Alg)
 	if err != nil {
 		return err
 	}
@@ -144,12 +146,6 @@ func (cmd *StageCommitCmd) Run(g *globals) error {
 	if err != nil {
 		return err
 	}
-	if cmd.Name == "" {
-		cmd.Name = g.getenv(envVarUserName)
-	}
-	if cmd.Email == "" {
-		cmd.Email = g.getenv(envVarUserEmail)
-	}
 	stage, err := stageFile.Stage()
 	if err != nil {
 		return fmt.Errorf("stage has errors: %w", err)
@@ -159,7 +155,7 @@ func (cmd *StageCommitCmd) Run(g *globals) error {
 		obj,
 		stage,
 		cmd.Message,
-		newUser(cmd.Name, cmd.Email),
+		g.user(cmd.Name, cmd.Email),
 		g.logger)
 	if err != nil {
 		return err
@@ -322,6 +318,31 @@ func (cmd *StageStatusCmd) Run(g *globals) error {
 	return nil
 }
 
+// user returns the user for a new object version. If name or email are empty,
+// values from the environment or the storage root's config are used.
+func (g *globals) user(name string, email string) ocfl.User {
+	conf := g.rootConfig()
+	name = cmp.Or(name, g.getenv(envVarUserName), conf.UserName)
+	email = cmp.Or(email, g.getenv(envVarUserEmail), conf.UserEmail)
+	return newUser(name, email)
+}
+
+// newStageFile returns a new stage for the object. If alg is empty, the
+// storage root's configured digest algorithm or sha512 is used. Fixity
+// algorithms from the storage root's config are included for new content.
+func (g *globals) newStageFile(obj *ocfl.Object, alg string) (*stage.StageFile, error) {
+	conf := g.rootConfig()
+	stageFile, err := stage.NewStageFile(obj, cmp.Or(alg, conf.DigestAlgorithm, defaultDigestAlg))
+	if err != nil {
+		return nil, err
+	}
+	stageFile.FixityIDs = slices.Clone(conf.FixityAlgorithms)
+	if _, err := stageFile.Algs(); err != nil {
+		return nil, err
+	}
+	return stageFile, nil
+}
+
 func newUser(name string, email string) ocfl.User {
 	if email != "" && !strings.HasPrefix(`email:`, email) {
 		email = "email:" + email
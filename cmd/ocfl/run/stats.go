@@ -0,0 +1,292 @@
+package run
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"path"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/srerickson/ocfl-go"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"golang.org/x/sync/errgroup"
+)
+
+const statsHelp = "Show statistics for the storage root or an object"
+
+type StatsCmd struct {
+	ID   string `name:"id" short:"i" optional:"" help:"The ID of an object to show per-version statistics for"`
+	JSON bool   `name:"json" help:"print statistics as JSON"`
+	Top  int    `name:"top" default:"5" help:"number of objects to list as largest and with the most versions"`
+	Jobs int    `name:"jobs" short:"j" default:"0" help:"number of objects to read concurrently. Defaults to the number of CPU cores."`
+}
+
+// objectStats are statistics for a single object. Content values are for
+// files stored in the object; logical values are for files in every version
+// state, as if there were no deduplication.
+type objectStats struct {
+	ID              string         `json:"id"`
+	Spec            string         `json:"spec"`
+	DigestAlgorithm string         `json:"digest_algorithm"`
+	Versions        int            `json:"versions"`
+	ContentFiles    int            `json:"content_files"`
+	ContentBytes    int64          `json:"content_bytes"`
+	LogicalFiles    int            `json:"logical_files"`
+	LogicalBytes    int64          `json:"logical_bytes"`
+	VersionStats    []versionStats `json:"version_stats,omitempty"`
+}
+
+// versionStats are statistics for an object version. Added values are for
+// content files stored in the version directory.
+type versionStats struct {
+	Version    string    `json:"version"`
+	Created    time.Time `json:"created"`
+	Files      int       `json:"files"`
+	Bytes      int64     `json:"bytes"`
+	AddedFiles int       `json:"added_files"`
+	AddedBytes int64     `json:"added_bytes"`
+}
+
+// rootStats are aggregate statistics for all objects in a storage root.
+type rootStats struct {
+	Objects          int            `json:"objects"`
+	Versions         int            `json:"versions"`
+	ContentFiles     int            `json:"content_files"`
+	ContentBytes     int64          `json:"content_bytes"`
+	LogicalFiles     int            `json:"logical_files"`
+	LogicalBytes     int64          `json:"logical_bytes"`
+	DedupSavings     int64          `json:"dedup_savings_bytes"`
+	DigestAlgorithms map[string]int `json:"digest_algorithms"`
+	Specs            map[string]int `json:"specs"`
+	Largest          []objectStats  `json:"largest"`
+	MostVersions     []objectStats  `json:"most_versions"`
+}
+
+func (cmd *StatsCmd) Run(g *globals) error {
+	ctx := g.ctx
+	if cmd.Top < 0 {
+		return errors.New("--top must be >= 0")
+	}
+	if cmd.ID != "" {
+		obj, err := g.newObject(cmd.ID, "", ocfl.ObjectMustExist())
+		if err != nil {
+			return err
+		}
+		stats, err := newObjectStats(ctx, obj)
+		if err != nil {
+			return err
+		}
+		if cmd.JSON {
+			return printJSON(g.stdout, stats)
+		}
+		stats.print(g.stdout)
+		return nil
+	}
+	jobs := cmd.Jobs
+	if jobs < 1 {
+		jobs = runtime.NumCPU()
+	}
+	root, err := g.getRoot()
+	if err != nil {
+		return err
+	}
+	var (
+		objStats    []*objectStats
+		mx          sync.Mutex
+		grp, grpCtx = errgroup.WithContext(ctx)
+	)
+	grp.SetLimit(jobs)
+	for obj, err := range root.ObjectsBatch(ctx, jobs) {
+		if err != nil {
+			grp.Wait()
+			return fmt.Errorf("while listing objects in the storage root: %w", err)
+		}
+		grp.Go(func() error {
+			stats, err := newObjectStats(grpCtx, obj)
+			if err != nil {
+				return err
+			}
+			stats.VersionStats = nil
+			mx.Lock()
+			defer mx.Unlock()
+			objStats = append(objStats, stats)
+			return nil
+		})
+	}
+	if err := grp.Wait(); err != nil {
+		return err
+	}
+	stats := newRootStats(objStats, cmd.Top)
+	if cmd.JSON {
+		return printJSON(g.stdout, stats)
+	}
+	stats.print(g.stdout)
+	return nil
+}
+
+// newObjectStats returns statistics for obj, including content sizes.
+func newObjectStats(ctx context.Context, obj *ocfl.Object) (*objectStats, error) {
+	fileSizes, err := contentFileSizes(ctx, obj)
+	if err != nil {
+		return nil, fmt.Errorf("reading content sizes for object %q: %w", obj.ID(), err)
+	}
+	stats := &objectStats{
+		ID:              obj.ID(),
+		Spec:            string(obj.Spec()),
+		DigestAlgorithm: obj.DigestAlgorithm().ID(),
+		Versions:        obj.Head().Num(),
+	}
+	digestSizes := map[string]int64{}
+	addedFiles := map[string]int{}
+	addedBytes := map[string]int64{}
+	for contentPath, dig := range obj.Manifest().Paths() {
+		size := fileSizes[contentPath]
+		digestSizes[dig] = size
+		stats.ContentFiles++
+		stats.ContentBytes += size
+		vdir, _, _ := strings.Cut(contentPath, "/")
+		addedFiles[vdir]++
+		addedBytes[vdir] += size
+	}
+	for _, vnum := range obj.Head().Lineage() {
+		ver := obj.Version(vnum.Num())
+		vstats := versionStats{
+			Version:    vnum.String(),
+			Created:    ver.Created(),
+			AddedFiles: addedFiles[vnum.String()],
+			AddedBytes: addedBytes[vnum.String()],
+		}
+		for _, dig := range ver.State().Paths() {
+			vstats.Files++
+			vstats.Bytes += digestSizes[dig]
+		}
+		stats.LogicalFiles += vstats.Files
+		stats.LogicalBytes += vstats.Bytes
+		stats.VersionStats = append(stats.VersionStats, vstats)
+	}
+	return stats, nil
+}
+
+// contentFileSizes returns the sizes of the object's content files, indexed by
+// their path relative to the object root. If the object's backend doesn't
+// support listing directories (e.g., http), each content file is stat'd.
+func contentFileSizes(ctx context.Context, obj *ocfl.Object) (map[string]int64, error) {
+	sizes, err := objectFileSizes(ctx, obj)
+	if !errors.Is(err, ocflfs.ErrOpUnsupported) {
+		return sizes, err
+	}
+	sizes = map[string]int64{}
+	for name := range obj.Manifest().Paths() {
+		info, err := ocflfs.StatFile(ctx, obj.FS(), path.Join(obj.Path(), name))
+		if err != nil {
+			return nil, err
+		}
+		sizes[name] = info.Size()
+	}
+	return sizes, nil
+}
+
+func newRootStats(objects []*objectStats, top int) *rootStats {
+	stats := &rootStats{
+		DigestAlgorithms: map[string]int{},
+		Specs:            map[string]int{},
+	}
+	for _, obj := range objects {
+		stats.Objects++
+		stats.Versions += obj.Versions
+		stats.ContentFiles += obj.ContentFiles
+		stats.ContentBytes += obj.ContentBytes
+		stats.LogicalFiles += obj.LogicalFiles
+		stats.LogicalBytes += obj.LogicalBytes
+		stats.DigestAlgorithms[obj.DigestAlgorithm]++
+		stats.Specs[obj.Spec]++
+	}
+	stats.DedupSavings = stats.LogicalBytes - stats.ContentBytes
+	topObjects := func(compare func(a, b *objectStats) int) []objectStats {
+		sorted := slices.Clone(objects)
+		slices.SortFunc(sorted, func(a, b *objectStats) int {
+			return cmp.Or(compare(b, a), cmp.Compare(a.ID, b.ID))
+		})
+		result := []objectStats{}
+		for _, obj := range sorted[:min(top, len(sorted))] {
+			result = append(result, *obj)
+		}
+		return result
+	}
+	stats.Largest = topObjects(func(a, b *objectStats) int {
+		return cmp.Compare(a.ContentBytes, b.ContentBytes)
+	})
+	stats.MostVersions = topObjects(func(a, b *objectStats) int {
+		return cmp.Compare(a.Versions, b.Versions)
+	})
+	return stats
+}
+
+func (s *objectStats) print(w io.Writer) {
+	fmt.Fprintln(w, "id:", s.ID)
+	fmt.Fprintln(w, "OCFL version:", s.Spec)
+	fmt.Fprintln(w, "digest algorithm:", s.DigestAlgorithm)
+	fmt.Fprintf(w, "content: %d file(s), %s\n", s.ContentFiles, formatBytes(s.ContentBytes))
+	fmt.Fprintf(w, "logical: %d file(s), %s\n", s.LogicalFiles, formatBytes(s.LogicalBytes))
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tCREATED\tFILES\tSIZE\tADDED FILES\tADDED SIZE")
+	for _, v := range s.VersionStats {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%d\t%s\n", v.Version, v.Created.Format(time.RFC3339),
+			v.Files, formatBytes(v.Bytes), v.AddedFiles, formatBytes(v.AddedBytes))
+	}
+	tw.Flush()
+}
+
+func (s *rootStats) print(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "objects:\t%d\n", s.Objects)
+	fmt.Fprintf(tw, "versions:\t%d\n", s.Versions)
+	fmt.Fprintf(tw, "content:\t%d file(s), %s\n", s.ContentFiles, formatBytes(s.ContentBytes))
+	fmt.Fprintf(tw, "logical:\t%d file(s), %s\n", s.LogicalFiles, formatBytes(s.LogicalBytes))
+	fmt.Fprintf(tw, "dedup savings:\t%s\n", formatBytes(s.DedupSavings))
+	fmt.Fprintf(tw, "digest algorithms:\t%s\n", formatCounts(s.DigestAlgorithms))
+	fmt.Fprintf(tw, "OCFL versions:\t%s\n", formatCounts(s.Specs))
+	tw.Flush()
+	if len(s.Largest) > 0 {
+		fmt.Fprintln(w, "\nlargest objects:")
+		tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, obj := range s.Largest {
+			fmt.Fprintf(tw, "  %s\t%s\n", obj.ID, formatBytes(obj.ContentBytes))
+		}
+		tw.Flush()
+	}
+	if len(s.MostVersions) > 0 {
+		fmt.Fprintln(w, "\nobjects with the most versions:")
+		tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, obj := range s.MostVersions {
+			fmt.Fprintf(tw, "  %s\t%d\n", obj.ID, obj.Versions)
+		}
+		tw.Flush()
+	}
+}
+
+// formatCounts formats counts as "key (n), ...", sorted by key.
+func formatCounts(counts map[string]int) string {
+	var parts []string
+	for _, key := range slices.Sorted(maps.Keys(counts)) {
+		parts = append(parts, fmt.Sprintf("%s (%d)", key, counts[key]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// printJSON writes v to w as indented JSON.
+func printJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
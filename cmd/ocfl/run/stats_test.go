@@ -0,0 +1,111 @@
+package run_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/testutil"
+)
+
+func TestStats(t *testing.T) {
+	_, fixtures := testutil.TempDirTestData(t,
+		`testdata/store-fixtures/1.0/good-stores/reg-extension-dir-root`,
+		`testdata/content-fixture`,
+	)
+	root := fixtures[0]
+	contentFixture := fixtures[1]
+	id := "ark:123/abc"
+	args := []string{`commit`, `--root`, root, `--id`, id, `-m`, "update", `-n`, "Tester", contentFixture}
+	testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+		be.NilErr(t, err)
+	})
+
+	t.Run("root", func(t *testing.T) {
+		args := []string{`stats`, `--root`, root}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "objects:", stdout)
+			be.In(t, "sha512 (1)", stdout)
+			be.In(t, "largest objects:", stdout)
+		})
+	})
+
+	t.Run("negative top", func(t *testing.T) {
+		args := []string{`stats`, `--root`, root, `--top=-1`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.True(t, err != nil)
+			be.In(t, "--top must be >= 0", stderr)
+		})
+	})
+
+	t.Run("root json", func(t *testing.T) {
+		args := []string{`stats`, `--root`, root, `--json`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			var stats struct {
+				Objects      int            `json:"objects"`
+				Versions     int            `json:"versions"`
+				ContentBytes int64          `json:"content_bytes"`
+				Specs        map[string]int `json:"specs"`
+				MostVersions []struct {
+					ID string `json:"id"`
+				} `json:"most_versions"`
+			}
+			be.NilErr(t, json.Unmarshal([]byte(stdout), &stats))
+			be.Equal(t, 1, stats.Objects)
+			be.Equal(t, 2, stats.Versions)
+			be.True(t, stats.ContentBytes > 0)
+			be.Equal(t, 1, stats.Specs["1.0"])
+			be.Equal(t, id, stats.MostVersions[0].ID)
+		})
+	})
+
+	t.Run("object", func(t *testing.T) {
+		args := []string{`stats`, `--root`, root, `--id`, id, `--json`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			var stats struct {
+				VersionStats []struct {
+					Version    string `json:"version"`
+					AddedFiles int    `json:"added_files"`
+					AddedBytes int64  `json:"added_bytes"`
+				} `json:"version_stats"`
+			}
+			be.NilErr(t, json.Unmarshal([]byte(stdout), &stats))
+			be.Equal(t, 2, len(stats.VersionStats))
+			be.Equal(t, "v1", stats.VersionStats[0].Version)
+			be.Equal(t, 1, stats.VersionStats[0].AddedFiles)
+			be.Equal(t, int64(20), stats.VersionStats[0].AddedBytes)
+			be.True(t, stats.VersionStats[1].AddedFiles > 0)
+		})
+	})
+
+	t.Run("json without html escaping", func(t *testing.T) {
+		id := "a&b<c>"
+		args := []string{`commit`, `--root`, root, `--id`, id, `-m`, "add", `-n`, "Tester", contentFixture}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+		})
+		args = []string{`stats`, `--root`, root, `--id`, id, `--json`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, `"id": "a&b<c>"`, stdout)
+		})
+	})
+
+	t.Run("object over http", func(t *testing.T) {
+		srv := httptest.NewServer(http.FileServer(http.FS(testutil.TestDataFS())))
+		defer srv.Close()
+		rootURL, err := url.JoinPath(srv.URL, "testdata", "store-fixtures", "1.0", "good-stores", "reg-extension-dir-root")
+		be.NilErr(t, err)
+		args := []string{`stats`, `--root`, rootURL, `--id`, id}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "content: 1 file(s), 20 B", stdout)
+		})
+	})
+}
@@ -0,0 +1,344 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/srerickson/ocfl-go"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"golang.org/x/sync/errgroup"
+)
+
+const syncHelp = "Mirror objects from one storage root to another, transferring only new versions"
+
+type SyncCmd struct {
+	From             string `name:"from" required:"" help:"location of the source storage root"`
+	To               string `name:"to" required:"" help:"location of the destination storage root. It is created if it doesn't exist."`
+	Jobs             int    `name:"jobs" short:"j" default:"0" help:"number of objects to synchronize concurrently. Defaults to the number of CPU cores."`
+	DryRun           bool   `name:"dry-run" help:"report changes without transferring or deleting anything"`
+	DeleteExtraneous bool   `name:"delete-extraneous" help:"delete objects in the destination that are not in the source"`
+}
+
+// syncStatus describes the outcome of synchronizing a single object.
+type syncStatus string
+
+const (
+	syncNew       syncStatus = "new"
+	syncUpdated   syncStatus = "updated"
+	syncUnchanged syncStatus = "unchanged"
+	syncFailed    syncStatus = "failed"
+)
+
+// syncReport summarizes a sync run
+type syncReport struct {
+	mx         sync.Mutex
+	objects    map[syncStatus]int
+	versions   int
+	files      int
+	bytes      int64
+	extraneous int
+}
+
+func (r *syncReport) add(status syncStatus, versions, files int, size int64) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if r.objects == nil {
+		r.objects = map[syncStatus]int{}
+	}
+	r.objects[status]++
+	r.versions += versions
+	r.files += files
+	r.bytes += size
+}
+
+func (cmd *SyncCmd) Run(g *globals) error {
+	ctx := g.ctx
+	src, err := g.openRoot(cmd.From)
+	if err != nil {
+		return fmt.Errorf("in --from: %w", err)
+	}
+	if src.Layout() == nil {
+		return fmt.Errorf("source storage root has no layout: %w", ocfl.ErrLayoutUndefined)
+	}
+	dst, err := cmd.dstRoot(g, src)
+	if err != nil {
+		return err
+	}
+	if dst != nil && dst.Layout() == nil {
+		return fmt.Errorf("destination storage root has no layout: %w", ocfl.ErrLayoutUndefined)
+	}
+	jobs := cmd.Jobs
+	if jobs < 1 {
+		jobs = runtime.NumCPU()
+	}
+	report := &syncReport{}
+	srcIDs := map[string]bool{}
+	grp := &errgroup.Group{}
+	grp.SetLimit(jobs)
+	for obj, err := range src.Objects(ctx) {
+		if err != nil {
+			grp.Wait()
+			return fmt.Errorf("while listing objects in source root: %w", err)
+		}
+		srcIDs[obj.ID()] = true
+		grp.Go(func() error {
+			logger := g.logger.With("object_id", obj.ID())
+			versions, files, size, status, err := cmd.syncObject(ctx, obj, dst)
+			if err != nil {
+				logger.Error("object not synchronized", "err", err.Error())
+				report.add(syncFailed, 0, 0, 0)
+				return nil
+			}
+			if status != syncUnchanged {
+				logger.Info("object "+string(status), "versions", versions, "files", files, "dry_run", cmd.DryRun)
+			}
+			report.add(status, versions, files, size)
+			return nil
+		})
+	}
+	grp.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if cmd.DeleteExtraneous && dst != nil {
+		var extraneous []*ocfl.Object
+		for obj, err := range dst.Objects(ctx) {
+			if err != nil {
+				return fmt.Errorf("while listing objects in destination root: %w", err)
+			}
+			if !srcIDs[obj.ID()] {
+				extraneous = append(extraneous, obj)
+			}
+		}
+		for _, obj := range extraneous {
+			report.extraneous++
+			g.logger.Info("deleting extraneous object", "object_id", obj.ID(), "dry_run", cmd.DryRun)
+			if cmd.DryRun {
+				continue
+			}
+			if err := ocflfs.RemoveAll(ctx, obj.FS(), obj.Path()); err != nil {
+				return fmt.Errorf("deleting %q: %w", obj.ID(), err)
+			}
+		}
+	}
+	report.print(g, cmd.DryRun)
+	if failed := report.objects[syncFailed]; failed > 0 {
+		return fmt.Errorf("%d object(s) could not be synchronized", failed)
+	}
+	return nil
+}
+
+// dstRoot returns the destination storage root, initializing it with the
+// source root's spec and layout if necessary. The returned root is nil for dry
+// runs with a destination that doesn't exist.
+func (cmd *SyncCmd) dstRoot(g *globals, src *ocfl.Root) (*ocfl.Root, error) {
+	ctx := g.ctx
+	fsys, dir, err := g.parseLocation(cmd.To)
+	if err != nil {
+		return nil, fmt.Errorf("in --to: %w", err)
+	}
+	if _, isWriteFS := fsys.(ocflfs.WriteFS); !isWriteFS {
+		return nil, fmt.Errorf("destination storage root is not writable: %s", locationString(fsys, dir))
+	}
+	dst, err := ocfl.NewRoot(ctx, fsys, dir)
+	if err == nil {
+		return dst, nil
+	}
+	entries, readErr := ocflfs.ReadDir(ctx, fsys, dir)
+	if readErr != nil && !errors.Is(readErr, fs.ErrNotExist) {
+		return nil, readErr
+	}
+	if len(entries) > 0 {
+		return nil, fmt.Errorf("reading destination storage root %s: %w", locationString(fsys, dir), err)
+	}
+	if cmd.DryRun {
+		g.logger.Info("destination storage root would be created", "dry_run", true)
+		return nil, nil
+	}
+	dst, err = ocfl.NewRoot(ctx, fsys, dir, ocfl.InitRoot(src.Spec(), src.Description(), src.Layout()))
+	if err != nil {
+		return nil, fmt.Errorf("while initializing destination storage root: %w", err)
+	}
+	g.logger.Info("created destination storage root", "root", locationString(fsys, dir))
+	return dst, nil
+}
+
+// syncObject transfers new versions of obj to the destination root. It returns
+// the number of versions and files transferred and the total size of the
+// transferred files.
+func (cmd *SyncCmd) syncObject(ctx context.Context, obj *ocfl.Object, dst *ocfl.Root) (int, int, int64, syncStatus, error) {
+	srcHead := obj.Head().Num()
+	if dst == nil {
+		// dry run without a destination
+		files, size, err := objectFiles(ctx, obj, 0, func(string) error { return nil })
+		return srcHead, files, size, syncNew, err
+	}
+	dstPath, err := dst.ResolveID(obj.ID())
+	if err != nil {
+		return 0, 0, 0, syncFailed, err
+	}
+	dstFS := dst.FS()
+	dstDir := path.Join(dst.Path(), dstPath)
+	status := syncNew
+	dstHead := 0
+	dstObj, err := dst.NewObject(ctx, obj.ID())
+	switch {
+	case err != nil:
+		// The destination object may be incomplete because of an interrupted
+		// sync, in which case all files are transferred again.
+		if !incompleteReplica(ctx, obj, dstFS, dstDir, err) {
+			return 0, 0, 0, syncFailed, err
+		}
+	case dstObj.Exists():
+		status = syncUpdated
+		dstHead = dstObj.Head().Num()
+		if err := checkReplica(ctx, obj, dstObj); err != nil {
+			return 0, 0, 0, syncFailed, err
+		}
+		if dstHead == srcHead {
+			return 0, 0, 0, syncUnchanged, nil
+		}
+	}
+	copyFn := func(name string) error {
+		if cmd.DryRun {
+			return nil
+		}
+		_, err := ocflfs.Copy(ctx, dstFS, path.Join(dstDir, name), obj.FS(), path.Join(obj.Path(), name))
+		return err
+	}
+	files, size, err := objectFiles(ctx, obj, dstHead, copyFn)
+	if err != nil {
+		return 0, 0, 0, syncFailed, err
+	}
+	return srcHead - dstHead, files, size, status, nil
+}
+
+// incompleteReplica returns true if err, returned when opening the destination
+// object at dstDir, indicates that a previous transfer of obj was interrupted.
+func incompleteReplica(ctx context.Context, obj *ocfl.Object, dstFS ocflfs.FS, dstDir string, err error) bool {
+	if errors.Is(err, fs.ErrNotExist) {
+		return true
+	}
+	// the root inventory was transferred but not its sidecar.
+	inv, invErr := ocfl.ReadInventory(ctx, dstFS, dstDir)
+	return invErr == nil && inv.Digest() == obj.InventoryDigest()
+}
+
+// checkReplica returns an error if dstObj is not an earlier (or the same) state
+// of obj.
+func checkReplica(ctx context.Context, obj, dstObj *ocfl.Object) error {
+	srcHead := obj.Head().Num()
+	dstHead := dstObj.Head().Num()
+	if dstHead > srcHead {
+		return fmt.Errorf("destination object has more versions (%d) than the source (%d)", dstHead, srcHead)
+	}
+	if obj.DigestAlgorithm().ID() != dstObj.DigestAlgorithm().ID() {
+		return errors.New("source and destination objects use different digest algorithms")
+	}
+	if dstHead == srcHead {
+		if obj.InventoryDigest() != dstObj.InventoryDigest() {
+			return errors.New("source and destination objects have different inventories for the same head")
+		}
+		return nil
+	}
+	// compare the destination's root inventory to the source's inventory for
+	// the same version.
+	verDir := path.Join(obj.Path(), obj.Version(dstHead).VNum().String())
+	verDigest, err := ocfl.ReadInventorySidecar(ctx, obj.FS(), verDir, obj.DigestAlgorithm().ID())
+	if err == nil {
+		if !strings.EqualFold(verDigest, dstObj.InventoryDigest()) {
+			return fmt.Errorf("destination object has diverged from the source at version %d", dstHead)
+		}
+		return nil
+	}
+	// without a version inventory, compare the version blocks
+	for v := 1; v <= dstHead; v++ {
+		srcVer, dstVer := obj.Version(v), dstObj.Version(v)
+		if dstVer == nil || !srcVer.State().Eq(dstVer.State()) || !srcVer.Created().Equal(dstVer.Created()) || srcVer.Message() != dstVer.Message() {
+			return fmt.Errorf("destination object has diverged from the source at version %d", v)
+		}
+	}
+	return nil
+}
+
+// objectFiles calls fn for each file in obj that is not part of a version
+// directory up to and including fromHead. Files are visited in an order that
+// keeps the destination object valid if the process is interrupted: version
+// contents first, then the root inventory, and finally the root inventory's
+// sidecar. It returns the number of files and their total size.
+func objectFiles(ctx context.Context, obj *ocfl.Object, fromHead int, fn func(name string) error) (int, int64, error) {
+	skip := map[string]bool{}
+	for v := 1; v <= fromHead; v++ {
+		skip[obj.Version(v).VNum().String()] = true
+	}
+	var files []*ocflfs.FileRef
+	var rootInv []*ocflfs.FileRef
+	for file, err := range ocflfs.WalkFiles(ctx, obj.FS(), obj.Path()) {
+		if err != nil {
+			return 0, 0, err
+		}
+		first, _, _ := strings.Cut(file.Path, "/")
+		switch {
+		case skip[first]:
+			continue
+		case strings.HasPrefix(file.Path, "inventory.json"):
+			rootInv = append(rootInv, file)
+		default:
+			files = append(files, file)
+		}
+	}
+	// inventory.json before its sidecar
+	if len(rootInv) > 1 && rootInv[0].Path != "inventory.json" {
+		rootInv[0], rootInv[1] = rootInv[1], rootInv[0]
+	}
+	var size int64
+	for _, file := range append(files, rootInv...) {
+		if file.Info == nil {
+			if err := file.Stat(ctx); err != nil {
+				return 0, 0, err
+			}
+		}
+		if err := fn(file.Path); err != nil {
+			return 0, 0, err
+		}
+		size += file.Info.Size()
+	}
+	return len(files) + len(rootInv), size, nil
+}
+
+func (r *syncReport) print(g *globals, dryRun bool) {
+	transferred, deleted := "transferred", "deleted"
+	if dryRun {
+		fmt.Fprintln(g.stdout, "dry run: no changes were made")
+		transferred, deleted = "to transfer", "to delete"
+	}
+	total := 0
+	for _, n := range r.objects {
+		total += n
+	}
+	fmt.Fprintf(g.stdout, "objects:    %d (%d new, %d updated, %d unchanged, %d failed)\n",
+		total, r.objects[syncNew], r.objects[syncUpdated], r.objects[syncUnchanged], r.objects[syncFailed])
+	fmt.Fprintf(g.stdout, "versions:   %d %s\n", r.versions, transferred)
+	fmt.Fprintf(g.stdout, "files:      %d %s (%s)\n", r.files, transferred, formatBytes(r.bytes))
+	fmt.Fprintf(g.stdout, "extraneous: %d %s\n", r.extraneous, deleted)
+}
+
+// formatBytes returns a human readable representation of a size in bytes.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,122 @@
+package run_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/testutil"
+)
+
+func TestSync(t *testing.T) {
+	tmpDir, fixtures := testutil.TempDirTestData(t,
+		`testdata/store-fixtures/1.0/good-stores/reg-extension-dir-root`,
+		`testdata/content-fixture`,
+	)
+	srcRoot := fixtures[0]
+	contentFixture := fixtures[1]
+	dstRoot := filepath.Join(tmpDir, "replica")
+	id := "ark:123/abc"
+
+	t.Run("dry run", func(t *testing.T) {
+		args := []string{`sync`, `--from`, srcRoot, `--to`, dstRoot, `--dry-run`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "dry run", stdout)
+			be.In(t, "1 new", stdout)
+			be.In(t, "versions:   1 to transfer", stdout)
+		})
+		_, err := os.Stat(dstRoot)
+		be.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("new root", func(t *testing.T) {
+		args := []string{`sync`, `--from`, srcRoot, `--to`, dstRoot}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "1 new", stdout)
+		})
+		args = []string{`validate`, `--root`, dstRoot}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+		})
+		// second run doesn't transfer anything
+		args = []string{`sync`, `--from`, srcRoot, `--to`, dstRoot}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "1 unchanged", stdout)
+			be.In(t, "files:      0 transferred", stdout)
+		})
+	})
+
+	t.Run("interrupted", func(t *testing.T) {
+		// replicas with content but an incomplete root inventory
+		for _, removed := range [][]string{
+			{"inventory.json", "inventory.json.sha512"},
+			{"inventory.json.sha512"},
+		} {
+			replica := filepath.Join(t.TempDir(), "replica")
+			args := []string{`sync`, `--from`, srcRoot, `--to`, replica}
+			testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+				be.NilErr(t, err)
+			})
+			objDir := filepath.Join(replica, "a47", "817", "83d", "cec", "ark%3a123%2fabc")
+			for _, name := range removed {
+				be.NilErr(t, os.Remove(filepath.Join(objDir, name)))
+			}
+			testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+				be.NilErr(t, err)
+				be.In(t, "1 new", stdout)
+			})
+			args = []string{`validate`, `--root`, replica}
+			testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+				be.NilErr(t, err)
+			})
+		}
+	})
+
+	t.Run("new version", func(t *testing.T) {
+		args := []string{`commit`, `--root`, srcRoot, `--id`, id, `-m`, "update", `-n`, "Tester", contentFixture}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+		})
+		args = []string{`sync`, `--from`, srcRoot, `--to`, dstRoot}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "1 updated", stdout)
+			be.In(t, "versions:   1 transferred", stdout)
+		})
+		args = []string{`validate`, `--root`, dstRoot}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+		})
+		args = []string{`ls`, `--root`, dstRoot, `--id`, id}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "hello.csv", stdout)
+		})
+	})
+
+	t.Run("delete extraneous", func(t *testing.T) {
+		args := []string{`delete`, `--root`, srcRoot, `--id`, id, `--yes`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+		})
+		args = []string{`sync`, `--from`, srcRoot, `--to`, dstRoot, `--delete-extraneous`, `--dry-run`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "extraneous: 1 to delete", stdout)
+		})
+		args = []string{`sync`, `--from`, srcRoot, `--to`, dstRoot, `--delete-extraneous`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "extraneous: 1 deleted", stdout)
+		})
+		args = []string{`ls`, `--root`, dstRoot}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.Equal(t, "", stdout)
+		})
+	})
+}
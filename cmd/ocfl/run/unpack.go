@@ -0,0 +1,75 @@
+package run
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/srerickson/ocfl-go"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+)
+
+const unpackHelp = "Validate an object in a zip or tar file and install it in the storage root"
+
+type UnpackCmd struct {
+	Archive string `arg:"" name:"archive" help:"zip or tar file with the object to install"`
+	Dir     string `name:"dir" short:"d" default:"." help:"The object's directory in the archive. Defaults to the archive root, where pack writes the object's files."`
+}
+
+func (cmd *UnpackCmd) Run(g *globals) error {
+	ctx := g.ctx
+	format, err := archiveFormat(cmd.Archive)
+	if err != nil {
+		return err
+	}
+	srcFS, err := openArchiveFile(format, cmd.Archive)
+	if err != nil {
+		return err
+	}
+	defer srcFS.Close()
+	srcDir, err := locationPrefix(cmd.Dir)
+	if err != nil {
+		return fmt.Errorf("in --dir: %w", err)
+	}
+	logger := g.logger.With("object_path", locationString(srcFS, srcDir))
+	result := ocfl.ValidateObject(ctx, srcFS, srcDir, ocfl.ValidationLogger(logger))
+	if err := result.Err(); err != nil {
+		return fmt.Errorf("packed object is not valid: %w", err)
+	}
+	obj, err := ocfl.NewObject(ctx, srcFS, srcDir, ocfl.ObjectMustExist())
+	if err != nil {
+		return err
+	}
+	root, err := g.getRoot()
+	if err != nil {
+		return err
+	}
+	dstFS, isWriteFS := root.FS().(ocflfs.WriteFS)
+	if !isWriteFS {
+		return fmt.Errorf("storage root is not writable: %s", locationString(root.FS(), root.Path()))
+	}
+	objPath, err := root.ResolveID(obj.ID())
+	if err != nil {
+		return fmt.Errorf("resolving object path for %q: %w", obj.ID(), err)
+	}
+	dstDir := path.Join(root.Path(), objPath)
+	dstObj, err := root.NewObject(ctx, obj.ID())
+	switch {
+	case err != nil:
+		// resume an interrupted unpack
+		if !incompleteReplica(ctx, obj, dstFS, dstDir, err) {
+			return err
+		}
+	case dstObj.Exists():
+		return fmt.Errorf("object already exists in the storage root: %s", obj.ID())
+	}
+	copyFn := func(name string) error {
+		_, err := ocflfs.Copy(ctx, dstFS, path.Join(dstDir, name), srcFS, path.Join(srcDir, name))
+		return err
+	}
+	files, size, err := objectFiles(ctx, obj, 0, copyFn)
+	if err != nil {
+		return fmt.Errorf("installing object: %w", err)
+	}
+	g.logger.Info("unpacked object", "object_id", obj.ID(), "object_path", objPath, "versions", obj.Head().Num(), "files", files, "size", formatBytes(size))
+	return nil
+}
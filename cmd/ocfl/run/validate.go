@@ -1,22 +1,29 @@
 package run
 
 import (
-	"errors"
 	"fmt"
 	"log/slog"
+	"runtime"
 
 	"github.com/srerickson/ocfl-go"
+	"golang.org/x/sync/errgroup"
 )
 
-const validateHelp = "Validate an object or all objects in the storage root"
+const validateHelp = "Validate an object or the storage root and all its objects"
 
 type ValidateCmd struct {
-	ID         string `name:"id" short:"i" optional:"" help:"The id of object to validate"`
-	ObjPath    string `name:"object" help:"full path to object root. If set, --root and --id are ignored."`
-	SkipDigest bool   `name:"skip-digest" help:"skip digest (checksum) validation"`
+	ID             string `name:"id" short:"i" optional:"" help:"The id of object to validate"`
+	ObjPath        string `name:"object" help:"full path to object root. If set, --root and --id are ignored."`
+	SkipDigest     bool   `name:"skip-digest" help:"skip digest (checksum) validation"`
+	Jobs           int    `name:"jobs" short:"j" default:"0" help:"number of objects to validate concurrently when validating the storage root. Defaults to the number of CPU cores."`
+	Report         string `name:"report" enum:",json,jsonl,junit" default:"" help:"print a validation report to stdout in the given format: json, jsonl, or junit"`
+	FailOnWarnings bool   `name:"fail-on-warnings" help:"exit with an error if validation finds warnings"`
 }
 
 func (cmd *ValidateCmd) Run(g *globals) error {
+	// read files from the storage root, not the cache, to detect damage.
+	g.noCache = true
+	report := &validationReport{}
 	switch {
 	case cmd.ObjPath != "":
 		fsys, dir, err := g.parseLocation(cmd.ObjPath)
@@ -25,9 +32,7 @@ func (cmd *ValidateCmd) Run(g *globals) error {
 		}
 		logger := g.logger.With("object_path", locationString(fsys, dir))
 		result := ocfl.ValidateObject(g.ctx, fsys, dir, cmd.validationOptions(logger)...)
-		if result.Err() != nil {
-			return errors.New("object has errors")
-		}
+		report.Objects = append(report.Objects, newValidationEntry("object", dir, "", &result.Validation))
 	case cmd.ID != "":
 		root, err := g.getRoot()
 		if err != nil {
@@ -35,34 +40,39 @@ func (cmd *ValidateCmd) Run(g *globals) error {
 		}
 		logger := g.logger.With("object_id", cmd.ID)
 		result := root.ValidateObject(g.ctx, cmd.ID, cmd.validationOptions(logger)...)
-		if result.Err() != nil {
-			return errors.New("object has errors")
-		}
+		objPath, _ := root.ResolveID(cmd.ID)
+		report.Objects = append(report.Objects, newValidationEntry("object", objPath, cmd.ID, &result.Validation))
 	default:
-		// FIXME
-		g.logger.Warn("root validation is not fully implemented: validating all objects in the root, but not conformance of root structure itself. [https://github.com/srerickson/ocfl-go/issues/98]")
-		root, err := g.getRoot()
+		fsys, dir, err := g.parseLocation(g.RootLocation)
 		if err != nil {
-			return err
+			return fmt.Errorf("in storage root location: %w", err)
+		}
+		rootResult := validateRootStructure(g.ctx, fsys, dir, g.logger)
+		rootEntry := newValidationEntry("root", locationString(fsys, dir), "", &rootResult.Validation)
+		report.Root = &rootEntry
+		report.Objects = make([]validationEntry, len(rootResult.objects))
+		jobs := cmd.Jobs
+		if jobs < 1 {
+			jobs = runtime.NumCPU()
 		}
-		var badObjs []string
-		for decl, err := range root.ObjectDeclarations(g.ctx) {
-			if err != nil {
-				return fmt.Errorf("finding objects in the storage root: %w", err)
-			}
-			objFS := decl.FS
-			objDir := decl.FullPathDir()
-			g.logger = g.logger.With("object_path", objDir)
-			result := ocfl.ValidateObject(g.ctx, objFS, objDir, cmd.validationOptions(g.logger)...)
-			if result.Err() != nil {
-				badObjs = append(badObjs, objDir)
-			}
+		grp := errgroup.Group{}
+		grp.SetLimit(jobs)
+		for i, objDir := range rootResult.objects {
+			grp.Go(func() error {
+				logger := g.logger.With("object_path", objDir)
+				result := ocfl.ValidateObject(g.ctx, fsys, objDir, cmd.validationOptions(logger)...)
+				report.Objects[i] = newValidationEntry("object", relPath(dir, objDir), "", &result.Validation)
+				return nil
+			})
 		}
-		if l := len(badObjs); l > 0 {
-			return fmt.Errorf("found %d object(s) with errors", l)
+		grp.Wait()
+	}
+	if cmd.Report != "" {
+		if err := report.write(g.stdout, cmd.Report); err != nil {
+			return fmt.Errorf("writing validation report: %w", err)
 		}
 	}
-	return nil
+	return report.err(cmd.FailOnWarnings)
 }
 
 func (cmd *ValidateCmd) validationOptions(logger *slog.Logger) []ocfl.ObjectValidationOption {
@@ -0,0 +1,230 @@
+package run
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/srerickson/ocfl-go"
+)
+
+// validation status values used in reports
+const (
+	validationValid    = "valid"
+	validationWarnings = "warnings"
+	validationInvalid  = "invalid"
+)
+
+// validationReport is the machine-readable result of the validate command.
+type validationReport struct {
+	Root    *validationEntry  `json:"root,omitempty"`
+	Objects []validationEntry `json:"objects"`
+}
+
+// validationEntry is the validation result for a storage root or object.
+type validationEntry struct {
+	Type     string            `json:"type"` // "root" or "object"
+	Path     string            `json:"path"`
+	ID       string            `json:"id,omitempty"`
+	Status   string            `json:"status"`
+	Errors   []validationIssue `json:"errors"`
+	Warnings []validationIssue `json:"warnings"`
+}
+
+// validationIssue is an error or warning with its OCFL validation code, if
+// it has one.
+type validationIssue struct {
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+	URL     string `json:"url,omitempty"`
+}
+
+func (i validationIssue) String() string {
+	if i.Code == "" {
+		return i.Message
+	}
+	return i.Code + ": " + i.Message
+}
+
+// newValidationEntry returns a validationEntry of the given type ("root" or
+// "object") based on the validation result v.
+func newValidationEntry(typ string, path string, id string, v *ocfl.Validation) validationEntry {
+	entry := validationEntry{
+		Type:     typ,
+		Path:     path,
+		ID:       id,
+		Status:   validationValid,
+		Errors:   validationIssues(v.Errors()),
+		Warnings: validationIssues(v.WarnErrors()),
+	}
+	switch {
+	case len(entry.Errors) > 0:
+		entry.Status = validationInvalid
+	case len(entry.Warnings) > 0:
+		entry.Status = validationWarnings
+	}
+	return entry
+}
+
+func validationIssues(errs []error) []validationIssue {
+	issues := make([]validationIssue, len(errs))
+	for i, err := range errs {
+		issues[i].Message = err.Error()
+		var validErr *ocfl.ValidationError
+		if errors.As(err, &validErr) {
+			issues[i].Code = validErr.Code
+			issues[i].URL = validErr.URL
+		}
+	}
+	return issues
+}
+
+// entries returns the storage root entry (if any) followed by the object
+// entries.
+func (r *validationReport) entries() []validationEntry {
+	if r.Root == nil {
+		return r.Objects
+	}
+	return append([]validationEntry{*r.Root}, r.Objects...)
+}
+
+// write writes the report to w in the given format: "json", "jsonl", or
+// "junit".
+func (r *validationReport) write(w io.Writer, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case "jsonl":
+		enc := json.NewEncoder(w)
+		for _, entry := range r.entries() {
+			if err := enc.Encode(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "junit":
+		return r.writeJUnit(w)
+	default:
+		return fmt.Errorf("unsupported report format: %q", format)
+	}
+}
+
+// JUnit XML elements
+type (
+	junitTestSuites struct {
+		XMLName  xml.Name         `xml:"testsuites"`
+		Name     string           `xml:"name,attr"`
+		Tests    int              `xml:"tests,attr"`
+		Failures int              `xml:"failures,attr"`
+		Suites   []junitTestSuite `xml:"testsuite"`
+	}
+	junitTestSuite struct {
+		Name     string          `xml:"name,attr"`
+		Tests    int             `xml:"tests,attr"`
+		Failures int             `xml:"failures,attr"`
+		Cases    []junitTestCase `xml:"testcase"`
+	}
+	junitTestCase struct {
+		ClassName string        `xml:"classname,attr"`
+		Name      string        `xml:"name,attr"`
+		Failure   *junitFailure `xml:"failure,omitempty"`
+		SystemOut string        `xml:"system-out,omitempty"`
+	}
+	junitFailure struct {
+		Message string `xml:"message,attr"`
+		Type    string `xml:"type,attr"`
+		Text    string `xml:",chardata"`
+	}
+)
+
+// writeJUnit writes the report as JUnit XML: each object (and the storage
+// root) is a test case that fails if it has errors. Warnings are included as
+// the test case's output.
+func (r *validationReport) writeJUnit(w io.Writer) error {
+	suite := junitTestSuite{Name: "ocfl validate"}
+	for _, entry := range r.entries() {
+		name := entry.Path
+		if entry.ID != "" {
+			name = entry.ID
+		}
+		tc := junitTestCase{ClassName: entry.Type, Name: name}
+		if len(entry.Errors) > 0 {
+			lines := make([]string, len(entry.Errors))
+			for i, issue := range entry.Errors {
+				lines[i] = issue.String()
+			}
+			tc.Failure = &junitFailure{
+				Message: fmt.Sprintf("%d validation error(s)", len(entry.Errors)),
+				Type:    entry.Errors[0].Code,
+				Text:    strings.Join(lines, "\n"),
+			}
+			suite.Failures++
+		}
+		if len(entry.Warnings) > 0 {
+			lines := make([]string, len(entry.Warnings))
+			for i, issue := range entry.Warnings {
+				lines[i] = issue.String()
+			}
+			tc.SystemOut = strings.Join(lines, "\n")
+		}
+		suite.Cases = append(suite.Cases, tc)
+		suite.Tests++
+	}
+	suites := junitTestSuites{
+		Name:     suite.Name,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Suites:   []junitTestSuite{suite},
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// err returns an error if the report includes objects or a storage root with
+// errors, or with warnings if failOnWarnings is true.
+func (r *validationReport) err(failOnWarnings bool) error {
+	var badObjs, warnObjs int
+	for _, obj := range r.Objects {
+		switch obj.Status {
+		case validationInvalid:
+			badObjs++
+		case validationWarnings:
+			warnObjs++
+		}
+	}
+	if !failOnWarnings {
+		warnObjs = 0
+	}
+	rootStatus := validationValid
+	if r.Root != nil {
+		rootStatus = r.Root.Status
+	}
+	switch {
+	case r.Root == nil && badObjs > 0:
+		return errors.New("object has errors")
+	case r.Root == nil && warnObjs > 0:
+		return errors.New("object has warnings")
+	case badObjs > 0:
+		return fmt.Errorf("found %d object(s) with errors", badObjs)
+	case rootStatus == validationInvalid:
+		return errors.New("storage root has errors")
+	case warnObjs > 0:
+		return fmt.Errorf("found %d object(s) with warnings", warnObjs)
+	case failOnWarnings && rootStatus == validationWarnings:
+		return errors.New("storage root has warnings")
+	}
+	return nil
+}
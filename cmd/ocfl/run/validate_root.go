@@ -0,0 +1,328 @@
+package run
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/srerickson/ocfl-go"
+	"github.com/srerickson/ocfl-go/extension"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-go/validation"
+	"github.com/srerickson/ocfl-go/validation/code"
+
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/rootindex"
+)
+
+const (
+	layoutConfigFile = "ocfl_layout.json"
+	extensionsDir    = "extensions"
+	extensionConfig  = "config.json"
+)
+
+// rootValidation is the result of validating the structure of a storage root
+// (but not the objects it contains).
+type rootValidation struct {
+	ocfl.Validation
+	spec    string
+	logger  *slog.Logger
+	layout  extension.Layout
+	objects []string // object root directories, relative to the FS
+}
+
+// validateRootStructure validates the storage root at dir in fsys: its
+// declaration, layout configuration, extensions directory, and the storage
+// hierarchy. Object roots found in the storage hierarchy are included in the
+// result for validation.
+func validateRootStructure(ctx context.Context, fsys ocflfs.FS, dir string, logger *slog.Logger) *rootValidation {
+	v := &rootValidation{logger: logger, spec: string(ocfl.Spec1_1)}
+	var (
+		declarations []string            // storage root declarations
+		extDirs      = map[string]bool{} // extension directories
+		otherFiles   []string            // files in the storage hierarchy
+		objDecls     []string            // object declarations
+	)
+	for file, err := range ocflfs.WalkFiles(ctx, fsys, dir) {
+		if err != nil {
+			v.addFatal(fmt.Errorf("reading storage root: %w", err))
+			return v
+		}
+		name := file.Path
+		base := path.Base(name)
+		topLevel := !strings.Contains(name, "/")
+		switch {
+		case topLevel && strings.HasPrefix(base, "0="):
+			declarations = append(declarations, name)
+		case topLevel:
+			// other files in the storage root are ignored (E087).
+		case strings.HasPrefix(name, extensionsDir+"/"):
+			extName, rest, _ := strings.Cut(strings.TrimPrefix(name, extensionsDir+"/"), "/")
+			if rest == "" {
+				err := fmt.Errorf("extensions directory includes a file: %s", name)
+				v.addFatal(v.codeErr(err, code.E112, code.E086))
+				continue
+			}
+			extDirs[extName] = true
+		default:
+			if decl, err := ocfl.ParseNamaste(base); err == nil && decl.IsObject() {
+				objDecls = append(objDecls, name)
+				v.objects = append(v.objects, path.Join(dir, path.Dir(name)))
+			}
+			otherFiles = append(otherFiles, name)
+		}
+	}
+	v.checkDeclaration(ctx, fsys, dir, declarations)
+	// object declarations are checked against the storage root's OCFL version,
+	// which isn't known until the walk is complete.
+	for _, name := range objDecls {
+		v.checkObjectDeclaration(name)
+	}
+	v.checkLayout(ctx, fsys, dir)
+	for _, name := range slices.Sorted(maps.Keys(extDirs)) {
+		// the storage root index and audit ledger are maintained by this tool.
+		if name == rootindex.Extension || name == auditExtension {
+			continue
+		}
+		if !slices.Contains(extension.DefaultRegistry().Names(), name) {
+			err := fmt.Errorf("storage root extension is not registered: %s", name)
+			v.addWarn(v.codeErr(err, code.W016))
+		}
+	}
+	v.checkHierarchy(dir, otherFiles)
+	v.checkObjectPaths(ctx, fsys, dir)
+	return v
+}
+
+// checkDeclaration validates the storage root's NAMASTE declaration and sets
+// v's spec.
+func (v *rootValidation) checkDeclaration(ctx context.Context, fsys ocflfs.FS, dir string, declarations []string) {
+	switch len(declarations) {
+	case 0:
+		err := errors.New("storage root declaration not found")
+		v.addFatal(v.codeErr(err, code.E069))
+		return
+	case 1:
+	default:
+		err := fmt.Errorf("storage root has multiple declarations: %s", strings.Join(declarations, ", "))
+		v.addFatal(v.codeErr(err, code.E075))
+		return
+	}
+	name := declarations[0]
+	decl, err := ocfl.ParseNamaste(name)
+	if err != nil {
+		err = fmt.Errorf("invalid storage root declaration: %s", name)
+		v.addFatal(v.codeErr(err, code.E077))
+		return
+	}
+	if !decl.IsRoot() {
+		err := fmt.Errorf("storage root declaration has wrong type: %q", decl.Type)
+		v.addFatal(v.codeErr(err, code.E079))
+		return
+	}
+	if err := decl.Version.Valid(); err != nil {
+		v.addFatal(v.codeErr(fmt.Errorf("storage root declaration: %w", err), code.E075))
+		return
+	}
+	v.spec = string(decl.Version)
+	if err := ocfl.ValidateNamaste(ctx, fsys, path.Join(dir, name)); err != nil {
+		v.addFatal(v.codeErr(err, code.E080))
+	}
+}
+
+// checkLayout validates the storage root's ocfl_layout.json and the
+// configuration for the layout extension it references.
+func (v *rootValidation) checkLayout(ctx context.Context, fsys ocflfs.FS, dir string) {
+	byts, err := ocflfs.ReadAll(ctx, fsys, path.Join(dir, layoutConfigFile))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			v.addFatal(fmt.Errorf("reading %s: %w", layoutConfigFile, err))
+		}
+		return
+	}
+	var layoutConfig map[string]any
+	if err := json.Unmarshal(byts, &layoutConfig); err != nil {
+		err = fmt.Errorf("invalid %s: %w", layoutConfigFile, err)
+		v.addFatal(v.codeErr(err, code.E070))
+		return
+	}
+	for _, key := range []string{"extension", "description"} {
+		if _, isString := layoutConfig[key].(string); !isString {
+			err := fmt.Errorf("%s must include %q as a string", layoutConfigFile, key)
+			v.addFatal(v.codeErr(err, code.E070))
+		}
+	}
+	name, _ := layoutConfig["extension"].(string)
+	if name == "" {
+		return
+	}
+	registry := extension.DefaultRegistry()
+	if !slices.Contains(registry.Names(), name) {
+		err := fmt.Errorf("%s extension is not registered: %s", layoutConfigFile, name)
+		v.addWarn(v.codeErr(err, code.W016))
+		return
+	}
+	var ext extension.Extension
+	configName := path.Join(dir, extensionsDir, name, extensionConfig)
+	byts, err = ocflfs.ReadAll(ctx, fsys, configName)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		ext, err = registry.New(name)
+	case err == nil:
+		ext, err = registry.Unmarshal(byts)
+		if err == nil && ext.Name() != name {
+			err = fmt.Errorf("extension name in config is %q", ext.Name())
+		}
+	}
+	if err != nil {
+		err = fmt.Errorf("layout extension %s: %w", name, err)
+		v.addFatal(v.codeErr(err, code.E071))
+		return
+	}
+	layout, isLayout := ext.(extension.Layout)
+	if !isLayout {
+		err := fmt.Errorf("%s: %w", name, extension.ErrNotLayout)
+		v.addFatal(v.codeErr(err, code.E071))
+		return
+	}
+	if err := layout.Valid(); err != nil {
+		err = fmt.Errorf("layout extension %s has invalid configuration: %w", name, err)
+		v.addFatal(v.codeErr(err, code.E071))
+		return
+	}
+	v.layout = layout
+}
+
+// checkObjectDeclaration checks that the OCFL version in the object
+// declaration, name, is the same as or earlier than the storage root's.
+func (v *rootValidation) checkObjectDeclaration(name string) {
+	decl, err := ocfl.ParseNamaste(path.Base(name))
+	if err != nil || decl.Version.Valid() != nil || ocfl.Spec(v.spec).Valid() != nil {
+		// invalid object declarations are reported by the object validation
+		return
+	}
+	if decl.Version.Cmp(ocfl.Spec(v.spec)) > 0 {
+		err := fmt.Errorf("object declaration is for a later OCFL version than the storage root: %s", name)
+		v.addFatal(v.codeErr(err, code.E081))
+	}
+}
+
+// checkHierarchy reports files in the storage hierarchy that are not part of an
+// object, and objects nested inside other objects. Names are relative to the
+// storage root.
+func (v *rootValidation) checkHierarchy(dir string, names []string) {
+	objRoots := map[string]bool{}
+	intermediate := map[string]bool{} // directories that include object roots
+	for _, obj := range v.objects {
+		objDir := relPath(dir, obj)
+		objRoots[objDir] = true
+		for parent := path.Dir(objDir); parent != "."; parent = path.Dir(parent) {
+			intermediate[parent] = true
+		}
+	}
+	for _, obj := range v.objects {
+		objDir := relPath(dir, obj)
+		for parent := path.Dir(objDir); parent != "."; parent = path.Dir(parent) {
+			if objRoots[parent] {
+				err := fmt.Errorf("object is nested inside another object (%s): %s", parent, objDir)
+				v.addFatal(v.codeErr(err, code.E082))
+				break
+			}
+		}
+	}
+	for _, name := range names {
+		inObject := false
+		for parent := path.Dir(name); parent != "."; parent = path.Dir(parent) {
+			if objRoots[parent] {
+				inObject = true
+				break
+			}
+		}
+		switch {
+		case inObject:
+		case intermediate[path.Dir(name)]:
+			err := fmt.Errorf("file in an intermediate directory of the storage hierarchy: %s", name)
+			v.addFatal(v.codeErr(err, code.E084))
+		default:
+			err := fmt.Errorf("file is not part of an object: %s", name)
+			v.addFatal(v.codeErr(err, code.E072))
+		}
+	}
+}
+
+// checkObjectPaths reports objects whose paths don't match the path resolved
+// from their ID by the storage root's layout.
+func (v *rootValidation) checkObjectPaths(ctx context.Context, fsys ocflfs.FS, dir string) {
+	if v.layout == nil {
+		return
+	}
+	for _, obj := range v.objects {
+		inv, err := ocfl.ReadInventory(ctx, fsys, obj)
+		if err != nil {
+			// inventory errors are reported by the object validation
+			continue
+		}
+		objDir := relPath(dir, obj)
+		expected, err := v.layout.Resolve(inv.ID)
+		if err != nil {
+			err = fmt.Errorf("object ID can't be resolved by the storage root layout: %s: %w", objDir, err)
+			v.addWarn(v.codeErr(err, code.W014))
+			continue
+		}
+		if expected != objDir {
+			err := fmt.Errorf("object path doesn't match the storage root layout: %s (expected: %s)", objDir, expected)
+			v.addWarn(v.codeErr(err, code.W014))
+		}
+	}
+}
+
+// codeErr returns a *ocfl.ValidationError for err with the first validation
+// code from codes that is defined for the storage root's spec.
+func (v *rootValidation) codeErr(err error, codes ...func(string) *validation.ValidationCode) error {
+	for _, spec := range []string{v.spec, string(ocfl.Spec1_1), string(ocfl.Spec1_0)} {
+		for _, c := range codes {
+			if vc := c(spec); vc != nil {
+				return &ocfl.ValidationError{ValidationCode: *vc, Err: err}
+			}
+		}
+	}
+	return err
+}
+
+func (v *rootValidation) addFatal(err error) {
+	v.AddFatal(err)
+	logValidationErr(v.logger, slog.LevelError, err)
+}
+
+func (v *rootValidation) addWarn(err error) {
+	v.AddWarn(err)
+	logValidationErr(v.logger, slog.LevelWarn, err)
+}
+
+// logValidationErr logs err, including its OCFL validation code if it has one.
+func logValidationErr(logger *slog.Logger, level slog.Level, err error) {
+	if logger == nil {
+		return
+	}
+	var validErr *ocfl.ValidationError
+	if errors.As(err, &validErr) {
+		logger.Log(context.Background(), level, err.Error(), "ocfl_code", validErr.Code)
+		return
+	}
+	logger.Log(context.Background(), level, err.Error())
+}
+
+// relPath returns name relative to dir. Both must be valid io/fs paths, and
+// name must be in dir.
+func relPath(dir, name string) string {
+	if dir == "." {
+		return name
+	}
+	return strings.TrimPrefix(name, dir+"/")
+}
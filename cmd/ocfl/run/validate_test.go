@@ -1,7 +1,11 @@
 package run_test
 
 import (
+	"encoding/json"
+	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/carlmjohnson/be"
@@ -14,11 +18,13 @@ func TestValidate(t *testing.T) {
 		`testdata/object-fixtures/1.1/bad-objects`,
 		`testdata/store-fixtures/1.0/good-stores`,
 		`testdata/store-fixtures/1.0/bad-stores`,
+		`testdata/store-fixtures/1.0/warn-stores`,
 	)
 	goodObjectFixtures := fixtures[0]
 	badObjectFixtures := fixtures[1]
 	goodStoreFixtures := fixtures[2]
 	badStoreFixtures := fixtures[3]
+	warnStoreFixtures := fixtures[4]
 	t.Run("object fixtures", func(t *testing.T) {
 		// bad object
 		obj := filepath.Join(badObjectFixtures, `E010_missing_versions`)
@@ -65,4 +71,94 @@ func TestValidate(t *testing.T) {
 			be.In(t, "object(s) with errors", stderr)
 		})
 	})
+	t.Run("bad store structure", func(t *testing.T) {
+		args := []string{`validate`, `--root`, filepath.Join(badStoreFixtures, `E072_root_with_file_not_in_object`)}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.True(t, err != nil)
+			be.In(t, "ocfl_code=E072", stderr)
+		})
+		args = []string{`validate`, `--root`, goodObjectFixtures}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.True(t, err != nil)
+			be.In(t, "ocfl_code=E069", stderr)
+		})
+	})
+	t.Run("object declaration later than root", func(t *testing.T) {
+		_, fixtures := testutil.TempDirTestData(t, `testdata/store-fixtures/1.0/good-stores/reg-extension-dir-root`)
+		root := fixtures[0]
+		objDir := filepath.Join(root, "a47", "817", "83d", "cec", "ark%3a123%2fabc")
+		be.NilErr(t, os.Remove(filepath.Join(objDir, "0=ocfl_object_1.0")))
+		be.NilErr(t, os.WriteFile(filepath.Join(objDir, "0=ocfl_object_1.1"), []byte("ocfl_object_1.1\n"), 0644))
+		args := []string{`validate`, `--root`, root}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.True(t, err != nil)
+			be.In(t, "ocfl_code=E081", stderr)
+		})
+	})
+	t.Run("warn store fixtures", func(t *testing.T) {
+		args := []string{`validate`, `--root`, filepath.Join(warnStoreFixtures, `layout_wrong_path`)}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			be.In(t, "ocfl_code=W014", stderr)
+		})
+	})
+	t.Run("fail on warnings", func(t *testing.T) {
+		args := []string{`validate`, `--root`, filepath.Join(warnStoreFixtures, `layout_wrong_path`), `--fail-on-warnings`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.True(t, err != nil)
+			be.In(t, "storage root has warnings", stderr)
+		})
+	})
+	t.Run("json report", func(t *testing.T) {
+		root := filepath.Join(goodStoreFixtures, `reg-extension-dir-root`)
+		args := []string{`validate`, `--root`, root, `--report`, `json`, `--jobs`, `2`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.NilErr(t, err)
+			var report struct {
+				Root struct {
+					Status string `json:"status"`
+				} `json:"root"`
+				Objects []struct {
+					Path   string `json:"path"`
+					Status string `json:"status"`
+				} `json:"objects"`
+			}
+			be.NilErr(t, json.Unmarshal([]byte(stdout), &report))
+			be.Equal(t, "valid", report.Root.Status)
+			be.Equal(t, 1, len(report.Objects))
+			be.Equal(t, "valid", report.Objects[0].Status)
+		})
+	})
+	t.Run("jsonl report", func(t *testing.T) {
+		root := filepath.Join(badStoreFixtures, `multi_level_errors`)
+		args := []string{`validate`, `--root`, root, `--report`, `jsonl`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.True(t, err != nil)
+			lines := strings.Split(strings.TrimSpace(stdout), "\n")
+			be.True(t, len(lines) > 1)
+			var codes []string
+			for _, line := range lines {
+				var entry struct {
+					Type   string `json:"type"`
+					Errors []struct {
+						Code string `json:"code"`
+					} `json:"errors"`
+				}
+				be.NilErr(t, json.Unmarshal([]byte(line), &entry))
+				for _, e := range entry.Errors {
+					codes = append(codes, e.Code)
+				}
+			}
+			be.True(t, slices.Contains(codes, "E061"))
+		})
+	})
+	t.Run("junit report", func(t *testing.T) {
+		obj := filepath.Join(badObjectFixtures, `E010_missing_versions`)
+		args := []string{`validate`, `--object`, obj, `--report`, `junit`}
+		testutil.RunCLI(args, nil, func(err error, stdout string, stderr string) {
+			be.True(t, err != nil)
+			be.In(t, `<testsuites name="ocfl validate" tests="1" failures="1">`, stdout)
+			be.In(t, `type="E010"`, stdout)
+		})
+	})
 }
@@ -0,0 +1,20 @@
+package run
+
+import (
+	"github.com/srerickson/ocfl-tools/cmd/ocfl/internal/server"
+)
+
+const webdavHelp = "Serve a read-only WebDAV view of object versions in the storage root"
+
+type WebDAVCmd struct {
+	Addr string `name:"addr" default:":8080" help:"address to listen on"`
+}
+
+func (cmd *WebDAVCmd) Run(g *globals) error {
+	root, err := g.getRoot()
+	if err != nil {
+		return err
+	}
+	g.logger.Info("serving storage root with webdav", "root", locationString(root.FS(), root.Path()))
+	return listenAndServe(g, cmd.Addr, server.NewWebDAV(root, g.logger))
+}